@@ -0,0 +1,470 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkpoint saves the state of a scan so that it can be resumed.
+//
+// A checkpoint records the tasks accepted into the workqueue that are not yet
+// finished, the URLs that have been requested and the results found for them.
+// A task is finished once it and every task expanded from it are done.  When
+// a scan is resumed, the unfinished tasks are added again and expanded as
+// before, but the URLs already requested are filtered out and their results
+// replayed.  The values of secret headers are not saved, and are taken from
+// the settings of the resumed scan instead.
+//
+// The unfinished tasks and work counts are rewritten on each save, but the
+// results are appended to a log beside the checkpoint, so each save only
+// writes the results found since the last one.  The log and the memory used
+// by the checkpoint grow with the number of URLs requested.
+package checkpoint
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Version of the checkpoint file format
+const checkpointVersion = 2
+
+// Suffix of the results log kept beside a checkpoint
+const resultsLogSuffix = ".results"
+
+// Checkpoint holds the state of a scan.  It is safe for concurrent use.
+type Checkpoint struct {
+	path     string
+	baseURLs []string
+	// Unfinished tasks accepted into the workqueue, by key
+	pending map[string]*pendingTask
+	// Sequence number of the next task accepted
+	seq int
+	// Keys of tasks requested
+	done map[string]bool
+	// Results for the requested tasks
+	results []*results.Result
+	// Results written to the results log, or 0 if it is to be rewritten
+	logged int
+	// Work counter totals when last saved
+	workDone int64
+	workTodo int64
+	// Get the current work counter totals
+	counter func() (done, todo int64)
+	// Once done, tasks are not finished
+	ctx context.Context
+	// Has anything changed since the last save?
+	dirty bool
+	// Held while saving, so saves are written in order
+	saving sync.Mutex
+	sync.Mutex
+}
+
+// A task accepted into the workqueue that is not finished.
+type pendingTask struct {
+	task *task.Task
+	// Order the task was accepted in
+	seq int
+	// This task and those expanded from it that are not done
+	outstanding int
+	// Those that are done, which are counted again when resuming
+	finished int64
+}
+
+// Serialized form of a Checkpoint.  The results are in the results log.
+type checkpointFile struct {
+	Version  int          `json:"version"`
+	BaseURLs []string     `json:"base_urls"`
+	Queued   []*task.Task `json:"queued"`
+	// Number of results at the start of the log that belong to the checkpoint
+	Results  int   `json:"results"`
+	WorkDone int64 `json:"work_done"`
+	WorkTodo int64 `json:"work_todo"`
+}
+
+// Create an empty checkpoint to be saved to path.
+func NewCheckpoint(path string, baseURLs []string) *Checkpoint {
+	return &Checkpoint{
+		path:     path,
+		baseURLs: baseURLs,
+		pending:  make(map[string]*pendingTask),
+		done:     make(map[string]bool),
+	}
+}
+
+// Load a checkpoint from a file.  It will be saved back to the same file.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read checkpoint: %s", err.Error())
+	}
+	cf := &checkpointFile{}
+	if err := json.Unmarshal(data, cf); err != nil {
+		return nil, fmt.Errorf("Unable to parse checkpoint %s: %s", path, err.Error())
+	}
+	if cf.Version != checkpointVersion {
+		return nil, fmt.Errorf("Unsupported checkpoint version %d in %s", cf.Version, path)
+	}
+	c := NewCheckpoint(path, cf.BaseURLs)
+	// Kept until they are accepted and finished again
+	for _, t := range cf.Queued {
+		c.addPending(t)
+	}
+	if c.results, err = readResultsLog(path+resultsLogSuffix, cf.Results); err != nil {
+		return nil, err
+	}
+	for _, r := range c.results {
+		c.done[resultKey(r)] = true
+	}
+	c.workDone, c.workTodo = cf.WorkDone, cf.WorkTodo
+	logging.Logf(logging.LogInfo, "Loaded checkpoint %s: %d queued, %d done, %d results.",
+		path, len(c.pending), len(c.done), len(c.results))
+	return c, nil
+}
+
+// Read the first n results from a results log.  The log may have more, from
+// a save that was interrupted.
+func readResultsLog(path string, n int) ([]*results.Result, error) {
+	res := make([]*results.Result, 0, n)
+	if n == 0 {
+		return res, nil
+	}
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read checkpoint results: %s", err.Error())
+	}
+	defer fp.Close()
+	dec := json.NewDecoder(bufio.NewReader(fp))
+	for len(res) < n {
+		r := &results.Result{}
+		if err := dec.Decode(r); err == io.EOF {
+			return nil, fmt.Errorf("Checkpoint results %s has %d of %d results", path, len(res), n)
+		} else if err != nil {
+			return nil, fmt.Errorf("Unable to parse checkpoint results %s: %s", path, err.Error())
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// Set the path the checkpoint is saved to.
+func (c *Checkpoint) SetPath(path string) {
+	c.Lock()
+	defer c.Unlock()
+	c.path = path
+	c.logged = 0
+	c.dirty = true
+}
+
+// Set a function to get the work counter totals when saving.
+func (c *Checkpoint) SetCounter(f func() (done, todo int64)) {
+	c.Lock()
+	defer c.Unlock()
+	c.counter = f
+}
+
+// Stop finishing tasks once ctx is done.  The scan is then stopping, and
+// tasks are counted as done without being requested.
+func (c *Checkpoint) SetContext(ctx context.Context) {
+	c.Lock()
+	defer c.Unlock()
+	c.ctx = ctx
+}
+
+// Base URLs of the scan.
+func (c *Checkpoint) BaseURLs() []string {
+	return c.baseURLs
+}
+
+// Work counter totals when the checkpoint was last saved.  The work done
+// excludes that for unfinished tasks, which is done again when resuming.
+func (c *Checkpoint) WorkCounts() (done, todo int64) {
+	c.Lock()
+	defer c.Unlock()
+	return c.workDone, c.workTodo
+}
+
+// Record a task accepted into the workqueue, setting its Origin.
+func (c *Checkpoint) Accepted(t *task.Task) {
+	saved := t.Copy()
+	t.Origin = saved.String()
+	c.Lock()
+	defer c.Unlock()
+	c.addPending(saved).outstanding++
+}
+
+func (c *Checkpoint) addPending(t *task.Task) *pendingTask {
+	key := t.String()
+	p, ok := c.pending[key]
+	if !ok {
+		p = &pendingTask{task: t, seq: c.seq}
+		c.seq++
+		c.pending[key] = p
+		c.dirty = true
+	}
+	return p
+}
+
+// Record n tasks expanded from t.
+func (c *Checkpoint) Expanded(t *task.Task, n int) {
+	c.Lock()
+	defer c.Unlock()
+	if p, ok := c.pending[t.Origin]; ok {
+		p.outstanding += n
+	}
+}
+
+// Record that t is done.  The task it was expanded from is finished once
+// nothing expanded from it is outstanding.
+func (c *Checkpoint) Finished(t *task.Task) {
+	c.Lock()
+	defer c.Unlock()
+	if c.ctx != nil && c.ctx.Err() != nil {
+		return
+	}
+	p, ok := c.pending[t.Origin]
+	if !ok {
+		return
+	}
+	p.outstanding--
+	p.finished++
+	if p.outstanding <= 0 {
+		delete(c.pending, t.Origin)
+		c.dirty = true
+	}
+}
+
+// Record a result.  Results with errors are not recorded, so that the request
+// is retried when resuming.
+func (c *Checkpoint) AddResult(r *results.Result) {
+	if r.Error != nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.done[resultKey(r)] = true
+	c.results = append(c.results, r)
+	c.dirty = true
+}
+
+// Tasks that were queued and are not finished, in order.
+func (c *Checkpoint) Queued() []*task.Task {
+	c.Lock()
+	defer c.Unlock()
+	res := make([]*task.Task, 0, len(c.pending))
+	for _, t := range c.queued() {
+		res = append(res, t.Copy())
+	}
+	return res
+}
+
+func (c *Checkpoint) queued() []*task.Task {
+	pending := make([]*pendingTask, 0, len(c.pending))
+	for _, p := range c.pending {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].seq < pending[j].seq })
+	res := make([]*task.Task, 0, len(pending))
+	for _, p := range pending {
+		res = append(res, p.task)
+	}
+	return res
+}
+
+// Keys of the tasks that were done, as used by the WorkFilter.
+func (c *Checkpoint) Done() []string {
+	c.Lock()
+	defer c.Unlock()
+	res := make([]string, 0, len(c.done))
+	for k := range c.done {
+		res = append(res, k)
+	}
+	return res
+}
+
+// Results recorded so far.
+func (c *Checkpoint) Results() []*results.Result {
+	c.Lock()
+	defer c.Unlock()
+	return append([]*results.Result(nil), c.results...)
+}
+
+// Get the key of the task a result was produced for.
+func resultKey(r *results.Result) string {
+	t := &task.Task{URL: r.URL, Host: r.Host}
+	return t.String()
+}
+
+// Copy tasks with the values of their secret headers hidden.
+func redactTasks(tasks []*task.Task) []*task.Task {
+	res := make([]*task.Task, 0, len(tasks))
+	for _, t := range tasks {
+		t = t.Copy()
+		t.Header = settings.RedactHeader(t.Header)
+		res = append(res, t)
+	}
+	return res
+}
+
+// Save the checkpoint if anything has changed.  New results are appended to
+// the results log, and the checkpoint is then replaced atomically, so an
+// interrupted save leaves the previous checkpoint intact.
+func (c *Checkpoint) Save() error {
+	c.saving.Lock()
+	defer c.saving.Unlock()
+	c.Lock()
+	if c.counter != nil {
+		c.workDone, c.workTodo = c.counter()
+		for _, p := range c.pending {
+			c.workDone -= p.finished
+		}
+	}
+	if !c.dirty {
+		c.Unlock()
+		return nil
+	}
+	// Only copies are encoded, after unlocking
+	cf := &checkpointFile{
+		Version:  checkpointVersion,
+		BaseURLs: c.baseURLs,
+		Queued:   redactTasks(c.queued()),
+		Results:  len(c.results),
+		WorkDone: c.workDone,
+		WorkTodo: c.workTodo,
+	}
+	// Logs that may end with an interrupted save are rewritten
+	rewrite := c.logged == 0
+	unlogged := append([]*results.Result(nil), c.results[c.logged:]...)
+	path := c.path
+	c.dirty = false
+	c.Unlock()
+
+	err := writeResultsLog(path+resultsLogSuffix, unlogged, rewrite)
+	if err == nil {
+		err = writeCheckpointFile(path, cf)
+	}
+	c.Lock()
+	defer c.Unlock()
+	if err != nil {
+		c.dirty = true
+		c.logged = 0
+		return err
+	}
+	if c.path == path {
+		c.logged = cf.Results
+	}
+	logging.Logf(logging.LogDebug, "Saved checkpoint to %s.", path)
+	return nil
+}
+
+// Write results to a results log, replacing it if rewrite is set and
+// appending to it otherwise.
+func writeResultsLog(path string, res []*results.Result, rewrite bool) error {
+	if !rewrite && len(res) == 0 {
+		return nil
+	}
+	var fp *os.File
+	var err error
+	if rewrite {
+		fp, err = ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	} else {
+		fp, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to save checkpoint results: %s", err.Error())
+	}
+	buf := bufio.NewWriter(fp)
+	enc := json.NewEncoder(buf)
+	for _, r := range res {
+		if err = enc.Encode(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if rewrite {
+		if err == nil {
+			err = os.Rename(fp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(fp.Name())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to save checkpoint results: %s", err.Error())
+	}
+	return nil
+}
+
+// Replace the checkpoint file atomically.
+func writeCheckpointFile(path string, cf *checkpointFile) error {
+	data, err := json.Marshal(cf)
+	if err != nil {
+		return fmt.Errorf("Unable to encode checkpoint: %s", err.Error())
+	}
+	fp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Unable to save checkpoint: %s", err.Error())
+	}
+	_, err = fp.Write(data)
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(fp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(fp.Name())
+		return fmt.Errorf("Unable to save checkpoint: %s", err.Error())
+	}
+	return nil
+}
+
+// Save the checkpoint every interval until stop is closed, then save it a
+// final time.  The returned channel is closed once the final save is done.
+func (c *Checkpoint) RunInBackground(interval time.Duration, stop <-chan bool) <-chan bool {
+	finished := make(chan bool)
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Save(); err != nil {
+					logging.Logf(logging.LogWarning, "%s", err.Error())
+				}
+			case <-stop:
+				if err := c.Save(); err != nil {
+					logging.Logf(logging.LogError, "%s", err.Error())
+				}
+				return
+			}
+		}
+	}()
+	return finished
+}
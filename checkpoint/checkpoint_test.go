@@ -0,0 +1,247 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/task"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tempPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "scan.checkpoint")
+}
+
+func mustParse(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestCheckpoint_SaveLoad(t *testing.T) {
+	path := tempPath(t)
+	c := NewCheckpoint(path, []string{"http://localhost/"})
+	c.SetCounter(func() (int64, int64) { return 3, 10 })
+	c.Accepted(task.NewTaskFromURL(mustParse("http://localhost/")))
+	c.Accepted(task.NewTaskFromURL(mustParse("http://localhost/a/")))
+	c.Accepted(task.NewTaskFromURL(mustParse("http://localhost/")))
+	found := results.NewResult(mustParse("http://localhost/a/"), "")
+	found.Code = 200
+	c.AddResult(found)
+	failed := results.NewResult(mustParse("http://localhost/b"), "")
+	failed.Error = errors.New("connection reset")
+	c.AddResult(failed)
+	if err := c.Save(); err != nil {
+		t.Fatalf("Error saving checkpoint: %s", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("Error loading checkpoint: %s", err)
+	}
+	if b := loaded.BaseURLs(); len(b) != 1 || b[0] != "http://localhost/" {
+		t.Errorf("Unexpected base URLs: %v", b)
+	}
+	queued := loaded.Queued()
+	if len(queued) != 2 {
+		t.Fatalf("Expected 2 queued tasks, got %d", len(queued))
+	}
+	if queued[1].URL.Path != "/a/" {
+		t.Errorf("Expected /a/ queued second, got %s", queued[1].URL.Path)
+	}
+	done := loaded.Done()
+	if len(done) != 1 || done[0] != "http://localhost/a/" {
+		t.Errorf("Expected only /a/ done, got %v", done)
+	}
+	res := loaded.Results()
+	if len(res) != 1 || res[0].Code != 200 || res[0].URL.Path != "/a/" {
+		t.Errorf("Unexpected results: %v", res)
+	}
+	if d, todo := loaded.WorkCounts(); d != 3 || todo != 10 {
+		t.Errorf("Expected work counts 3/10, got %d/%d", d, todo)
+	}
+}
+
+func TestCheckpoint_Finished(t *testing.T) {
+	path := tempPath(t)
+	c := NewCheckpoint(path, []string{"http://localhost/"})
+	c.SetCounter(func() (int64, int64) { return 5, 8 })
+	ctx, cancel := context.WithCancel(context.Background())
+	c.SetContext(ctx)
+	root := task.NewTaskFromURL(mustParse("http://localhost/"))
+	c.Accepted(root)
+	dir := task.NewTaskFromURL(mustParse("http://localhost/a/"))
+	c.Accepted(dir)
+	// The root is expanded into two tasks, all of which are done
+	c.Expanded(root, 2)
+	for i := 0; i < 3; i++ {
+		c.Finished(root.Copy())
+	}
+	// The directory is expanded into one task, which is done, and the
+	// directory is skipped once the scan stops
+	c.Expanded(dir, 1)
+	c.Finished(dir.Copy())
+	cancel()
+	c.Finished(dir)
+	queued := c.Queued()
+	if len(queued) != 1 || queued[0].URL.Path != "/a/" {
+		t.Fatalf("Expected only /a/ queued, got %v", queued)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("Error saving checkpoint: %s", err)
+	}
+	// Work done for /a/ is done again when resuming
+	if d, todo := c.WorkCounts(); d != 4 || todo != 8 {
+		t.Errorf("Expected work counts 4/8, got %d/%d", d, todo)
+	}
+}
+
+func TestCheckpoint_SecretHeaders(t *testing.T) {
+	path := tempPath(t)
+	c := NewCheckpoint(path, []string{"http://localhost/"})
+	header := http.Header{"Authorization": {"Bearer hunter2"}, "X-Team": {"red"}}
+	tk := task.NewTaskFromURL(mustParse("http://localhost/"))
+	tk.Header = header
+	c.Accepted(tk)
+	r := results.NewResult(mustParse("http://localhost/"), "")
+	r.Code = 200
+	r.RequestHeader = header
+	c.AddResult(r)
+	if err := c.Save(); err != nil {
+		t.Fatalf("Error saving checkpoint: %s", err)
+	}
+	for _, p := range []string{path, path + resultsLogSuffix} {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "hunter2") {
+			t.Errorf("Expected secret headers not to be saved, got %s", data)
+		}
+	}
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("Error loading checkpoint: %s", err)
+	}
+	if h := loaded.Queued()[0].Header; h.Get("X-Team") != "red" {
+		t.Errorf("Expected other headers to be saved, got %v", h)
+	}
+	if header.Get("Authorization") != "Bearer hunter2" {
+		t.Error("Expected task headers to be unchanged.")
+	}
+}
+
+func TestCheckpoint_ResultsLog(t *testing.T) {
+	path := tempPath(t)
+	c := NewCheckpoint(path, []string{"http://localhost/"})
+	for _, p := range []string{"/a", "/b"} {
+		r := results.NewResult(mustParse("http://localhost"+p), "")
+		r.Code = 200
+		c.AddResult(r)
+		if err := c.Save(); err != nil {
+			t.Fatalf("Error saving checkpoint: %s", err)
+		}
+	}
+	data, err := ioutil.ReadFile(path + resultsLogSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("Expected each result to be logged once, got %d lines", lines)
+	}
+	// A save interrupted after appending to the log
+	fp, err := os.OpenFile(path+resultsLogSuffix, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp.Write([]byte(`{"url": "http://localhost/c", "co`))
+	fp.Close()
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("Error loading checkpoint: %s", err)
+	}
+	if res := loaded.Results(); len(res) != 2 || res[1].URL.Path != "/b" {
+		t.Fatalf("Expected 2 results, got %v", res)
+	}
+	r := results.NewResult(mustParse("http://localhost/c"), "")
+	loaded.AddResult(r)
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Error saving checkpoint: %s", err)
+	}
+	if loaded, err = LoadCheckpoint(path); err != nil {
+		t.Fatalf("Error loading rewritten checkpoint: %s", err)
+	}
+	if len(loaded.Results()) != 3 || len(loaded.Done()) != 3 {
+		t.Errorf("Expected 3 results, got %v", loaded.Results())
+	}
+}
+
+func TestLoadCheckpoint_Errors(t *testing.T) {
+	path := tempPath(t)
+	if _, err := LoadCheckpoint(path); err == nil {
+		t.Error("Expected error loading missing checkpoint.")
+	}
+	if err := ioutil.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(path); err == nil {
+		t.Error("Expected error loading invalid checkpoint.")
+	}
+	if err := ioutil.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(path); err == nil {
+		t.Error("Expected error loading unknown version.")
+	}
+	if err := ioutil.WriteFile(path, []byte(`{"version": 2, "results": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(path); err == nil {
+		t.Error("Expected error loading checkpoint without its results.")
+	}
+}
+
+func TestCheckpoint_RunInBackground(t *testing.T) {
+	path := tempPath(t)
+	c := NewCheckpoint(path, []string{"http://localhost/"})
+	stop := make(chan bool)
+	done := c.RunInBackground(time.Hour, stop)
+	c.Accepted(task.NewTaskFromURL(mustParse("http://localhost/")))
+	close(stop)
+	<-done
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("Expected final save, got error: %s", err)
+	}
+	if len(loaded.Queued()) != 1 {
+		t.Errorf("Expected 1 queued task, got %d", len(loaded.Queued()))
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"net"
+	"strings"
+)
+
+// Function to open a connection, as used by http.Transport.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// AddressAllowlist restricts connections to a set of IP ranges.  Host names
+// are resolved before connecting and the connection is made to a checked
+// address, so that a DNS change cannot lead outside the allowed ranges.
+type AddressAllowlist struct {
+	nets []*net.IPNet
+	// Resolve a host name
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Build an allowlist from CIDR ranges or single IP addresses.
+func ParseAllowlist(ranges []string) (*AddressAllowlist, error) {
+	a := &AddressAllowlist{lookup: net.DefaultResolver.LookupIPAddr}
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if !strings.Contains(r, "/") {
+			ip := net.ParseIP(r)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid IP address in allowlist: %s", r)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			a.nets = append(a.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(r)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR range in allowlist: %s", r)
+		}
+		a.nets = append(a.nets, ipnet)
+	}
+	if len(a.nets) == 0 {
+		return nil, fmt.Errorf("Allowlist has no ranges.")
+	}
+	return a, nil
+}
+
+// Check if an address is in an allowed range.
+func (a *AddressAllowlist) Allowed(ip net.IP) bool {
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap a dial function so that it only connects to allowed addresses.
+func (a *AddressAllowlist) Wrap(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := a.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range addrs {
+			if !a.Allowed(ip.IP) {
+				logging.Logf(logging.LogWarning, "Refusing connection to %s (%s): address not in allowlist.", host, ip.IP)
+				lastErr = fmt.Errorf("Refusing connection to %s (%s): address not in allowlist", host, ip.IP)
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("No addresses found for %s", host)
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	a, err := ParseAllowlist([]string{"10.0.0.0/8", " 192.168.1.5 ", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("Error parsing allowlist: %s", err)
+	}
+	cases := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"2001:db8::1", true},
+		{"::1", false},
+	}
+	for _, c := range cases {
+		if got := a.Allowed(net.ParseIP(c.ip)); got != c.allowed {
+			t.Errorf("Allowed(%s): expected %v, got %v", c.ip, c.allowed, got)
+		}
+	}
+	for _, bad := range [][]string{{"10.0.0.0/33"}, {"example.com"}, {}} {
+		if _, err := ParseAllowlist(bad); err == nil {
+			t.Errorf("Expected error parsing %v", bad)
+		}
+	}
+}
+
+func TestAddressAllowlist_Wrap(t *testing.T) {
+	a, err := ParseAllowlist([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "mixed.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("10.0.0.2")}}, nil
+		case "outside.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	var dialed []string
+	dial := a.Wrap(func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, nil
+	})
+	if _, err := dial(context.Background(), "tcp", "mixed.example.com:80"); err != nil {
+		t.Errorf("Expected allowed address to be dialed, got %s", err)
+	}
+	if len(dialed) != 1 || dialed[0] != "10.0.0.2:80" {
+		t.Errorf("Expected only 10.0.0.2:80 dialed, got %v", dialed)
+	}
+	dialed = nil
+	if _, err := dial(context.Background(), "tcp", "outside.example.com:80"); err == nil {
+		t.Error("Expected error for address outside allowlist.")
+	}
+	if _, err := dial(context.Background(), "tcp", "missing.example.com:80"); err == nil {
+		t.Error("Expected error for unresolvable host.")
+	}
+	if len(dialed) != 0 {
+		t.Errorf("Expected nothing dialed, got %v", dialed)
+	}
+}
+
+func TestProxyClientFactory_Allowlist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	for _, c := range []struct {
+		ranges  []string
+		allowed bool
+	}{
+		{[]string{"127.0.0.0/8"}, true},
+		{[]string{"10.0.0.0/8"}, false},
+	} {
+		fac, _ := NewProxyClientFactory(nil, time.Second, "")
+		allowlist, err := ParseAllowlist(c.ranges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fac.SetAllowlist(allowlist)
+		resp, err := fac.Get().RequestURL(u)
+		if c.allowed {
+			if err != nil {
+				t.Errorf("Expected request with %v to succeed, got %s", c.ranges, err)
+			} else {
+				resp.Body.Close()
+			}
+		} else if err == nil {
+			resp.Body.Close()
+			t.Errorf("Expected request with %v to be refused.", c.ranges)
+		}
+	}
+}
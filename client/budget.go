@@ -0,0 +1,79 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Returned by clients instead of making a request once the budget is used.
+var ErrBudgetExhausted = errors.New("Request budget exhausted.")
+
+// RequestBudget counts the HTTP requests made by all of the clients sharing
+// it, and optionally limits them.
+type RequestBudget struct {
+	limit       int64
+	used        int64
+	exhausted   func()
+	onceExhaust sync.Once
+}
+
+// Create a budget for limit requests, or an unlimited one if limit is 0.
+func NewRequestBudget(limit int64) *RequestBudget {
+	return &RequestBudget{limit: limit}
+}
+
+// Set a function to be called once, when the last request in the budget is
+// taken.  Must be set before the budget is used.
+func (b *RequestBudget) OnExhausted(f func()) {
+	b.exhausted = f
+}
+
+// Take a request from the budget, returning false if none are left.
+func (b *RequestBudget) Take() bool {
+	n := atomic.AddInt64(&b.used, 1)
+	if b.limit <= 0 {
+		return true
+	}
+	if n > b.limit {
+		atomic.AddInt64(&b.used, -1)
+		b.exhaust()
+		return false
+	}
+	if n == b.limit {
+		b.exhaust()
+	}
+	return true
+}
+
+func (b *RequestBudget) exhaust() {
+	b.onceExhaust.Do(func() {
+		if b.exhausted != nil {
+			b.exhausted()
+		}
+	})
+}
+
+// Number of requests made.
+func (b *RequestBudget) Used() int64 {
+	return atomic.LoadInt64(&b.used)
+}
+
+// Limit on requests, or 0 if unlimited.
+func (b *RequestBudget) Limit() int64 {
+	return b.limit
+}
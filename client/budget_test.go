@@ -0,0 +1,67 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRequestBudget_Unlimited(t *testing.T) {
+	b := NewRequestBudget(0)
+	b.OnExhausted(func() { t.Error("Unlimited budget should not be exhausted.") })
+	for i := 0; i < 100; i++ {
+		if !b.Take() {
+			t.Fatal("Expected unlimited budget to allow requests.")
+		}
+	}
+	if b.Used() != 100 {
+		t.Errorf("Expected 100 used, got %d", b.Used())
+	}
+}
+
+func TestRequestBudget_Limit(t *testing.T) {
+	b := NewRequestBudget(2)
+	exhausted := 0
+	b.OnExhausted(func() { exhausted++ })
+	if !b.Take() || exhausted != 0 {
+		t.Fatal("Expected first request to be allowed.")
+	}
+	if !b.Take() || exhausted != 1 {
+		t.Fatal("Expected last request to be allowed and exhaust the budget.")
+	}
+	if b.Take() {
+		t.Error("Expected request past the budget to be refused.")
+	}
+	if exhausted != 1 || b.Used() != 2 {
+		t.Errorf("Expected 2 used and 1 exhaustion, got %d and %d", b.Used(), exhausted)
+	}
+}
+
+func TestRequest_BudgetExhausted(t *testing.T) {
+	mockClient := makeMockHttpClient(&http.Response{StatusCode: 200}, &http.Response{StatusCode: 200})
+	c := &httpClient{Client: mockClient, budget: NewRequestBudget(1)}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	if _, err := c.Request(u, "", "GET", nil); err != nil {
+		t.Fatalf("Expected first request to succeed, got %v", err)
+	}
+	if _, err := c.Request(u, "", "GET", nil); err != ErrBudgetExhausted {
+		t.Errorf("Expected ErrBudgetExhausted, got %v", err)
+	}
+	if len(mockClient.resps) != 1 {
+		t.Error("Expected no request to be made past the budget.")
+	}
+}
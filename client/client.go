@@ -16,14 +16,24 @@
 package client
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/throttle"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
+// Returned by clients instead of making a request once the rate limiter is
+// stopped.
+var ErrRateLimiterStopped = errors.New("Rate limiter stopped.")
+
+// Most redirects followed for a single request, as for http.Client.
+const maxRedirects = 10
+
 // Client is a thin wrapper around http.Client to make enhancements to
 // support our use case.
 type Client interface {
@@ -46,6 +56,12 @@ type httpClient struct {
 	HTTPUsername string
 	HTTPPassword string
 	basicAuthStr string
+	// Shared count of requests, may be nil
+	budget *RequestBudget
+	// Shared rate limit, may be nil
+	limiter *throttle.RateLimiter
+	// Context for waiting for the rate limiter, may be nil
+	ctx context.Context
 }
 
 // Request the URL given.
@@ -61,7 +77,7 @@ func (c *httpClient) RequestURL(u *url.URL) (*http.Response, error) {
 // Handles HTTP Authentication & Custom Headers
 func (c *httpClient) Request(u *url.URL, host, method string, header http.Header) (*http.Response, error) {
 	req := c.makeRequest(u, method, host, header)
-	resp, err := c.Client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return resp, err
 	}
@@ -82,7 +98,7 @@ func (c *httpClient) Request(u *url.URL, host, method string, header http.Header
 			logging.Logf(logging.LogInfo, err.Error())
 			return resp, nil
 		}
-		resp, err = c.Client.Do(req)
+		resp, err = c.do(req)
 		if err != nil {
 			return resp, err
 		}
@@ -90,6 +106,47 @@ func (c *httpClient) Request(u *url.URL, host, method string, header http.Header
 	return resp, nil
 }
 
+// Make a single request, once the rate limit and budget allow it.  The
+// response is reported back to the rate limiter.
+func (c *httpClient) do(req *http.Request) (*http.Response, error) {
+	if err := c.admit(req); err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	// A redirect may have been refused by admit
+	if uerr, ok := err.(*url.Error); ok && (uerr.Err == ErrBudgetExhausted || uerr.Err == ErrRateLimiterStopped) {
+		err = uerr.Err
+	}
+	if resp != nil {
+		// After redirects, the response is from the last host requested
+		host := req.URL.Host
+		if resp.Request != nil {
+			host = resp.Request.URL.Host
+		}
+		c.limiter.Observe(host, resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	return resp, err
+}
+
+// Wait for the rate limit, then take a request from the budget.  Every
+// request, including each redirect followed, is admitted.
+func (c *httpClient) admit(req *http.Request) error {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !c.limiter.Wait(ctx, req.URL.Host) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return ErrRateLimiterStopped
+	}
+	if c.budget != nil && !c.budget.Take() {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
+
 // Build a request with our preferred options
 func (c *httpClient) makeRequest(u *url.URL, method, host string, header http.Header) *http.Request {
 	req, _ := http.NewRequest(method, u.String(), nil)
@@ -103,13 +160,24 @@ func (c *httpClient) makeRequest(u *url.URL, method, host string, header http.He
 	return req
 }
 
+// Set the policy for following redirects, or nil for the default policy.
+// Redirects that are followed are admitted like any other request.
 func (c *httpClient) SetCheckRedirect(checker func(*http.Request, []*http.Request) error) {
 	cli, ok := c.Client.(*http.Client)
 	if !ok {
 		logging.Logf(logging.LogError, "Unable to set CheckRedirect, type assertion failed.")
 		return
 	}
-	cli.CheckRedirect = checker
+	cli.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if checker != nil {
+			if err := checker(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= maxRedirects {
+			return fmt.Errorf("Stopped after %d redirects.", maxRedirects)
+		}
+		return c.admit(req)
+	}
 }
 
 // Add an authentication header in response to authHeader
@@ -15,11 +15,15 @@
 package client
 
 import (
+	"context"
 	"encoding/base64"
+	"github.com/Matir/webborer/throttle"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Mock httpClient that returns arbitrary responses
@@ -202,3 +206,96 @@ func TestRequestURL_DigestAuth(t *testing.T) {
 		t.Errorf("Got non-401 response code: %d", resp.StatusCode)
 	}
 }
+
+func TestRequest_RateLimiter(t *testing.T) {
+	mockResp := &http.Response{
+		StatusCode: 429,
+		Header:     make(http.Header),
+	}
+	limiter := throttle.NewRateLimiter(0, 0)
+	c := &httpClient{Client: makeMockHttpClient(mockResp), limiter: limiter}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	if _, err := c.Request(u, "", "GET", nil); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if limiter.Interval("localhost") == 0 {
+		t.Error("Expected limiter to back off after 429.")
+	}
+	limiter.Stop()
+	if _, err := c.Request(u, "", "GET", nil); err != ErrRateLimiterStopped {
+		t.Errorf("Expected ErrRateLimiterStopped, got %v", err)
+	}
+}
+
+func TestRequest_RedirectsAdmitted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/old")
+
+	factory, _ := NewProxyClientFactory(nil, time.Second, "test")
+	budget := NewRequestBudget(0)
+	factory.SetRequestBudget(budget)
+	resp, err := factory.Get().RequestURL(u)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Request.URL.Path != "/new" || budget.Used() != 2 {
+		t.Errorf("Expected redirect followed and counted, got %s after %d requests", resp.Request.URL.Path, budget.Used())
+	}
+
+	factory.SetRequestBudget(NewRequestBudget(1))
+	if _, err := factory.Get().RequestURL(u); err != ErrBudgetExhausted {
+		t.Errorf("Expected ErrBudgetExhausted for redirect, got %v", err)
+	}
+}
+
+func TestRequest_RedirectBackoff(t *testing.T) {
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer busy.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, busy.URL+"/", http.StatusFound)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/")
+	target, _ := url.Parse(busy.URL)
+
+	factory, _ := NewProxyClientFactory(nil, time.Second, "test")
+	limiter := throttle.NewRateLimiter(0, 0)
+	defer limiter.Stop()
+	factory.SetRateLimiter(limiter)
+	resp, err := factory.Get().RequestURL(u)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	resp.Body.Close()
+	if limiter.Interval(target.Host) == 0 {
+		t.Error("Expected limiter to back off the host redirected to.")
+	}
+	if limiter.Interval(u.Host) != 0 {
+		t.Error("Expected limiter not to back off the host redirected from.")
+	}
+}
+
+func TestRequest_Context(t *testing.T) {
+	limiter := throttle.NewRateLimiter(0, time.Hour)
+	defer limiter.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &httpClient{Client: makeMockHttpClient(&http.Response{StatusCode: 200}), limiter: limiter, ctx: ctx}
+	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	if _, err := c.Request(u, "", "GET", nil); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	cancel()
+	if _, err := c.Request(u, "", "GET", nil); err != context.Canceled {
+		t.Errorf("Expected wait for limiter to be cancelled, got %v", err)
+	}
+}
@@ -15,11 +15,14 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/throttle"
 	"h12.io/socks"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -46,6 +49,10 @@ type ProxyClientFactory struct {
 	userAgent    string
 	httpUsername string
 	httpPassword string
+	budget       *RequestBudget
+	allowlist    *AddressAllowlist
+	limiter      *throttle.RateLimiter
+	ctx          context.Context
 }
 
 // Create a ProxyClientFactory for the provided list of proxies.
@@ -75,43 +82,79 @@ func (factory *ProxyClientFactory) SetUsernamePassword(username, password string
 	factory.httpPassword = password
 }
 
+// Count requests made by clients from this factory against a budget.
+func (factory *ProxyClientFactory) SetRequestBudget(budget *RequestBudget) {
+	factory.budget = budget
+}
+
+// Wait for the rate limiter before each request made by clients from this
+// factory.
+func (factory *ProxyClientFactory) SetRateLimiter(limiter *throttle.RateLimiter) {
+	factory.limiter = limiter
+}
+
+// Stop clients from this factory waiting for the rate limiter once ctx is
+// done.  Requests already started are finished.
+func (factory *ProxyClientFactory) SetContext(ctx context.Context) {
+	factory.ctx = ctx
+}
+
+// Only connect to addresses in the allowlist, directly or through a proxy.
+func (factory *ProxyClientFactory) SetAllowlist(allowlist *AddressAllowlist) {
+	factory.allowlist = allowlist
+}
+
 // Get a single client instance from the factory
 func (factory *ProxyClientFactory) Get() Client {
+	var cli *httpClient
 	if len(factory.proxyURLs) == 0 {
-		return &httpClient{
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+		if factory.allowlist != nil {
+			transport.DialContext = factory.allowlist.Wrap((&net.Dialer{}).DialContext)
+		}
+		cli = &httpClient{
 			Client: &http.Client{
-				Timeout: factory.timeout,
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				},
+				Timeout:   factory.timeout,
+				Transport: transport,
 			},
-			UserAgent:    factory.userAgent,
-			HTTPUsername: factory.httpUsername,
-			HTTPPassword: factory.httpPassword,
+			UserAgent: factory.userAgent,
 		}
-	}
-	var cli *httpClient
-	if len(factory.proxyURLs) == 1 {
-		cli = clientForProxy(factory.proxyURLs[0], factory.timeout, factory.userAgent)
+	} else if len(factory.proxyURLs) == 1 {
+		cli = clientForProxy(factory.proxyURLs[0], factory.timeout, factory.userAgent, factory.allowlist)
 	} else {
 		proxy := factory.proxyURLs[rand.Intn(len(factory.proxyURLs))]
-		cli = clientForProxy(proxy, factory.timeout, factory.userAgent)
+		cli = clientForProxy(proxy, factory.timeout, factory.userAgent, factory.allowlist)
 	}
 	cli.HTTPUsername = factory.httpUsername
 	cli.HTTPPassword = factory.httpPassword
+	cli.budget = factory.budget
+	cli.limiter = factory.limiter
+	cli.ctx = factory.ctx
+	cli.SetCheckRedirect(nil)
 	return cli
 }
 
-// Build a client for a particular proxy instance
-func clientForProxy(proxy *url.URL, timeout time.Duration, agent string) *httpClient {
+// Build a client for a particular proxy instance.  With an allowlist, target
+// hosts are resolved locally and the proxy is asked to connect to the checked
+// address.
+func clientForProxy(proxy *url.URL, timeout time.Duration, agent string, allowlist *AddressAllowlist) *httpClient {
 	proto := proxyTypeMap[proxy.Scheme]
 	dialer := socks.DialSocksProxy(proto, proxy.Host)
+	transport := &http.Transport{
+		Dial: dialer,
+	}
+	if allowlist != nil {
+		transport.Dial = nil
+		transport.DialContext = allowlist.Wrap(func(_ context.Context, network, addr string) (net.Conn, error) {
+			return dialer(network, addr)
+		})
+	}
 	cl := &httpClient{
 		Client: &http.Client{
-			Transport: &http.Transport{
-				Dial: dialer,
-			},
-			Timeout: timeout,
+			Transport: transport,
+			Timeout:   timeout,
 		},
 		UserAgent: agent}
 	return cl
@@ -37,6 +37,8 @@ type MockClient struct {
 	Requests        []*url.URL
 	Redir           *url.URL
 	CheckRedirect   func(*http.Request, []*http.Request) error
+	// Errors returned, in order, before any response
+	Errors []error
 }
 
 func (f *MockClientFactory) Get() client.Client {
@@ -63,6 +65,11 @@ func (c *MockClient) Request(u *url.URL, host, method string, header http.Header
 			return nil, err
 		}
 	}
+	if len(c.Errors) > 0 {
+		err := c.Errors[0]
+		c.Errors = c.Errors[1:]
+		return nil, err
+	}
 	if c.ForeverResponse != nil {
 		return c.ForeverResponse, nil
 	}
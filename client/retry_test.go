@@ -0,0 +1,70 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://localhost/", Err: err}
+	}
+	cases := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{ErrBudgetExhausted, false},
+		{ErrRateLimiterStopped, false},
+		{errors.New("Refusing connection"), false},
+		{wrap(errors.New("Stop redirect.")), false},
+		{wrap(io.ErrUnexpectedEOF), true},
+		{wrap(&net.DNSError{Err: "no such host", Name: "nowhere"}), false},
+		{wrap(&net.DNSError{Err: "server misbehaving", IsTemporary: true}), true},
+		{wrap(&net.DNSError{Err: "timeout", IsTimeout: true}), true},
+		{wrap(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{wrap(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{wrap(&net.OpError{Op: "proxyconnect", Err: errors.New("unreachable")}), true},
+	}
+	for _, c := range cases {
+		if IsTransient(c.err) != c.transient {
+			t.Errorf("IsTransient(%v): expected %v", c.err, c.transient)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if d := RetryDelay(0, 3); d != 0 {
+		t.Errorf("Expected no delay without backoff, got %s", d)
+	}
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := RetryDelay(time.Second, attempt); d < max/2 || d > max {
+				t.Errorf("Attempt %d: expected delay in [%s, %s], got %s", attempt, max/2, max, d)
+			}
+		}
+	}
+	if d := RetryDelay(time.Second, 100); d > maxRetryDelay {
+		t.Errorf("Expected delay capped at %s, got %s", maxRetryDelay, d)
+	}
+}
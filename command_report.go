@@ -0,0 +1,88 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"net/url"
+	"os"
+)
+
+// Whether the report command should produce a link check report.
+var reportLinkCheck bool
+
+var reportCommand = &command{
+	name:    "report",
+	args:    "[RESULTS.json...]",
+	summary: "Render results saved with -format=json in another format.",
+	help: `
+Reads results saved by a scan with -format=json (from stdin if no files are
+given) and writes them in the format given by -format.`,
+	flags: ss.FlagsConfig | ss.FlagsLogging | ss.FlagsOutput,
+	setup: func(settings *ss.ScanSettings) {
+		settings.FlagSet().BoolVar(&reportLinkCheck, "linkcheck", false, "Report broken links instead of found paths.")
+	},
+	run: runReport,
+}
+
+func runReport(settings *ss.ScanSettings) error {
+	var loaded []*results.Result
+	if len(settings.Args()) == 0 {
+		res, err := results.ReadJSONResults(os.Stdin)
+		if err != nil {
+			return err
+		}
+		loaded = res
+	}
+	for _, path := range settings.Args() {
+		fp, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		res, err := results.ReadJSONResults(fp)
+		fp.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err.Error())
+		}
+		loaded = append(loaded, res...)
+	}
+	if len(loaded) == 0 {
+		return errors.New("No results to report.")
+	}
+
+	if reportLinkCheck {
+		settings.RunMode = ss.RunModeLinkCheck
+	}
+	// Reports are titled with the first starting URL.
+	root := url.URL{Scheme: loaded[0].URL.Scheme, Host: loaded[0].URL.Host, Path: "/"}
+	settings.BaseURLs = []string{root.String()}
+
+	rm, err := results.GetResultsManager(settings)
+	if err != nil {
+		return err
+	}
+	rchan := make(chan *results.Result)
+	rm.Run(rchan)
+	for _, r := range loaded {
+		r.ResultGroup = results.GetResultGroup(r)
+		rchan <- r
+	}
+	close(rchan)
+	rm.Wait()
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/robots"
+	ss "github.com/Matir/webborer/settings"
+	"io/ioutil"
+	"net/url"
+)
+
+var robotsCommand = &command{
+	name:    "robots",
+	args:    "URL|FILE [PATH...]",
+	summary: "Test paths against a robots.txt file.",
+	help: `
+Loads robots.txt from a site (given as a URL) or a local file.  Prints whether
+each PATH may be fetched by -user-agent, or the rules that apply to it if no
+PATH is given.  PATH may include a query string.`,
+	flags: ss.FlagsConfig | ss.FlagsLogging | ss.FlagsClient,
+	run:   runRobots,
+}
+
+func runRobots(settings *ss.ScanSettings) error {
+	args := settings.Args()
+	if len(args) == 0 {
+		settings.FlagSet().Usage()
+		return errors.New("A robots.txt URL or file is required.")
+	}
+	data, err := loadRobots(settings, args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		for _, rule := range data.GetRulesForUserAgent(settings.UserAgent) {
+			fmt.Println(rule.String())
+		}
+		return nil
+	}
+	for _, path := range args[1:] {
+		if data.Allowed(settings.UserAgent, path) {
+			fmt.Printf("allowed     %s\n", path)
+		} else {
+			fmt.Printf("disallowed  %s\n", path)
+		}
+	}
+	return nil
+}
+
+func loadRobots(settings *ss.ScanSettings, src string) (*robots.RobotsData, error) {
+	if u, err := url.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		factory, err := client.NewProxyClientFactory(settings.Proxies, settings.Timeout, settings.UserAgent)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to build client factory: %s", err.Error())
+		}
+		factory.SetUsernamePassword(settings.HTTPUsername, settings.HTTPPassword)
+		if len(settings.AllowedRanges) > 0 {
+			allowlist, err := client.ParseAllowlist(settings.AllowedRanges)
+			if err != nil {
+				return nil, err
+			}
+			factory.SetAllowlist(allowlist)
+		}
+		return robots.GetRobotsForURL(u, factory)
+	}
+	body, err := ioutil.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	return robots.ParseRobotsTxt(body)
+}
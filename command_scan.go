@@ -0,0 +1,46 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	ss "github.com/Matir/webborer/settings"
+)
+
+var scanCommand = &command{
+	name:    "scan",
+	args:    "URL...",
+	summary: "Enumerate paths on a web server using a wordlist.",
+	help: `
+Requests paths from the wordlist below each starting URL, following links and
+directories that are found.  With -mode=dotproduct, every word is instead
+appended to every starting URL exactly once.`,
+	flags: ss.FlagsConfig | ss.FlagsLogging | ss.FlagsClient | ss.FlagsScan | ss.FlagsWordlist | ss.FlagsEnumeration | ss.FlagsOutput,
+	run:   runScan,
+}
+
+var linkCheckCommand = &command{
+	name:    "linkcheck",
+	args:    "URL...",
+	summary: "Spider a site and report broken links.",
+	help: `
+Follows links in HTML pages below each starting URL and reports links that
+return an error status.`,
+	flags: ss.FlagsConfig | ss.FlagsLogging | ss.FlagsClient | ss.FlagsScan | ss.FlagsOutput,
+	run: func(settings *ss.ScanSettings) error {
+		settings.RunMode = ss.RunModeLinkCheck
+		settings.ParseHTML = true
+		return runScan(settings)
+	},
+}
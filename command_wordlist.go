@@ -0,0 +1,62 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Matir/webborer/filter"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/wordlist"
+	"os"
+)
+
+// Whether the wordlist command should list the built-in wordlists.
+var wordlistBuiltins bool
+
+var wordlistCommand = &command{
+	name:    "wordlist",
+	summary: "Print a wordlist as it would be used by a scan.",
+	help: `
+Prints the words from -wordlist (or the default built-in wordlist) after
+applying -cases and -slashes, one per line.  This can be used to export a
+built-in wordlist for editing.`,
+	flags: ss.FlagsConfig | ss.FlagsLogging | ss.FlagsWordlist,
+	setup: func(settings *ss.ScanSettings) {
+		settings.FlagSet().BoolVar(&wordlistBuiltins, "builtins", false, "List the names of the built-in wordlists.")
+	},
+	run: runWordlist,
+}
+
+func runWordlist(settings *ss.ScanSettings) error {
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	if wordlistBuiltins {
+		for _, name := range wordlist.BuiltinWordlists {
+			fmt.Fprintln(out, name)
+		}
+		return nil
+	}
+	words, err := wordlist.LoadWordlist(settings.WordlistPath)
+	if err != nil {
+		return fmt.Errorf("Unable to load wordlist: %s", err.Error())
+	}
+	expander := filter.NewWordlistExpander(words, settings.AddSlashes, settings.MangleCases)
+	expander.ProcessWordlist()
+	for _, w := range expander.Wordlist {
+		fmt.Fprintln(out, w)
+	}
+	return nil
+}
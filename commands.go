@@ -0,0 +1,146 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	ss "github.com/Matir/webborer/settings"
+	"os"
+	"strings"
+)
+
+// A command is one of the subcommands of webborer.  Each command accepts only
+// the flags in its flag groups, plus any it registers itself in setup.
+type command struct {
+	name string
+	// Arguments following the flags, for the usage line
+	args string
+	// One-line summary
+	summary string
+	// Longer description for the command's help
+	help string
+	// Flag groups accepted by the command
+	flags ss.FlagGroup
+	// Register additional flags, may be nil
+	setup func(*ss.ScanSettings)
+	// Run the command once settings are loaded
+	run func(*ss.ScanSettings) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		scanCommand,
+		linkCheckCommand,
+		reportCommand,
+		wordlistCommand,
+		robotsCommand,
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Run the command named by the first argument.  For compatibility, arguments
+// that don't start with a command name are run as a scan with every flag.
+func runCommand(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			if len(args) > 1 {
+				if cmd := findCommand(args[1]); cmd != nil {
+					cmd.newSettings().FlagSet().Usage()
+					return 0
+				}
+			}
+			printUsage()
+			return 0
+		}
+		if cmd := findCommand(args[0]); cmd != nil {
+			return cmd.execute(cmd.newSettings(), args[1:])
+		}
+	}
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+	legacy := &command{
+		name:  "scan",
+		flags: ss.FlagsAll,
+		run:   runScan,
+	}
+	return legacy.execute(ss.NewScanSettings(), args)
+}
+
+// Build the settings for this command, including its flags.
+func (cmd *command) newSettings() *ss.ScanSettings {
+	settings := ss.NewCommandSettings(cmd.name, cmd.flags)
+	if cmd.setup != nil {
+		cmd.setup(settings)
+	}
+	fs := settings.FlagSet()
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: webborer %s [flags] %s\n\n", cmd.name, cmd.args)
+		if cmd.help != "" {
+			fmt.Fprintf(os.Stderr, "%s\n\n", strings.TrimSpace(cmd.help))
+		}
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	return settings
+}
+
+// Load settings from the arguments and run the command.
+func (cmd *command) execute(settings *ss.ScanSettings, args []string) int {
+	if err := settings.Load(args); err != nil {
+		logging.Logf(logging.LogFatal, err.Error())
+		return 1
+	}
+	if cmd.flags&ss.FlagsLogging != 0 {
+		logging.ResetLog(settings.LogfilePath, settings.LogLevel)
+		logging.Logf(logging.LogInfo, "Flags: %s", settings)
+	}
+	if settings.DumpConfig {
+		if err := settings.WriteConfig(os.Stdout); err != nil {
+			logging.Logf(logging.LogFatal, "Unable to write config: %s", err.Error())
+			return 1
+		}
+		return 0
+	}
+	if err := cmd.run(settings); err == errInterrupted {
+		logging.Logf(logging.LogWarning, err.Error())
+		return exitInterrupted
+	} else if err != nil {
+		logging.Logf(logging.LogFatal, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: webborer <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"webborer help <command>\" for the flags of a command.\n")
+}
@@ -0,0 +1,266 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Query parameters commonly used to carry session IDs.  Parameters starting
+// with aspsessionid are also matched, as ASP appends a random suffix.
+var sessionParams = map[string]bool{
+	"sid":        true,
+	"sessid":     true,
+	"sessionid":  true,
+	"session_id": true,
+	"jsessionid": true,
+	"phpsessid":  true,
+	"cfid":       true,
+	"cftoken":    true,
+	"zenid":      true,
+	"oscsid":     true,
+}
+
+// Canonicalizer builds the keys used to recognise URLs that refer to the same
+// resource.  The scheme and host are always compared case-insensitively and
+// default ports are ignored; other rules are optional:
+//
+//	path      Remove dot segments and duplicate slashes.
+//	encoding  Decode needlessly escaped characters and use upper case escapes.
+//	case      Compare paths case-insensitively.
+//	query     Ignore the order of query parameters.
+//	session   Ignore common session ID parameters.
+//
+// Keys are only used for comparison; tasks are requested as found.
+type Canonicalizer struct {
+	cleanPath    bool
+	fixEncoding  bool
+	foldCase     bool
+	sortQuery    bool
+	dropSessions bool
+	// Lower case names of query parameters to ignore
+	dropParams map[string]bool
+}
+
+// Create a Canonicalizer with the named rules, ignoring the given query
+// parameters.  Unknown rules are ignored.
+func NewCanonicalizer(rules []string, dropParams []string) *Canonicalizer {
+	c := &Canonicalizer{dropParams: make(map[string]bool)}
+	for _, r := range rules {
+		switch strings.TrimSpace(r) {
+		case "path":
+			c.cleanPath = true
+		case "encoding":
+			c.fixEncoding = true
+		case "case":
+			c.foldCase = true
+		case "query":
+			c.sortQuery = true
+		case "session":
+			c.dropSessions = true
+		}
+	}
+	for _, p := range dropParams {
+		if p = strings.TrimSpace(p); p != "" {
+			c.dropParams[strings.ToLower(p)] = true
+		}
+	}
+	return c
+}
+
+// Get the key for a task, in the form of Task.String().
+func (c *Canonicalizer) Key(t *task.Task) string {
+	key := c.URL(t.URL)
+	if t.Host != "" {
+		key = key + " (" + t.Host + ")"
+	}
+	return key
+}
+
+// Get the key for a key produced by Task.String(), such as those saved in a
+// checkpoint.  Keys that can't be parsed are returned unchanged.
+func (c *Canonicalizer) StringKey(key string) string {
+	raw, host := key, ""
+	if i := strings.LastIndex(key, " ("); i != -1 && strings.HasSuffix(key, ")") {
+		raw, host = key[:i], key[i+2:len(key)-1]
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return key
+	}
+	return c.Key(&task.Task{URL: u, Host: host})
+}
+
+// Get the canonical form of a URL.  The fragment is dropped.
+func (c *Canonicalizer) URL(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Scheme + ":" + u.Opaque
+	}
+	var b strings.Builder
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "" {
+		b.WriteString(scheme)
+		b.WriteByte(':')
+	}
+	if scheme != "" || u.Host != "" {
+		b.WriteString("//")
+		if u.User != nil {
+			b.WriteString(u.User.String())
+			b.WriteByte('@')
+		}
+		b.WriteString(canonicalHost(scheme, u.Host))
+	}
+	b.WriteString(c.path(u))
+	if q := c.query(u.RawQuery); q != "" {
+		b.WriteByte('?')
+		b.WriteString(q)
+	}
+	return b.String()
+}
+
+func (c *Canonicalizer) path(u *url.URL) string {
+	p := u.EscapedPath()
+	if c.dropSessions {
+		p = stripPathSession(p)
+	}
+	// Decode first, so that escaped letters are folded too
+	if c.fixEncoding {
+		p = normalizeEscapes(p)
+	}
+	if c.foldCase {
+		p = strings.ToLower(p)
+	}
+	if c.cleanPath {
+		if p == "" && u.Host != "" {
+			return "/"
+		}
+		if p != "" {
+			cleaned := path.Clean(p)
+			if strings.HasSuffix(p, "/") && cleaned != "/" {
+				cleaned += "/"
+			}
+			p = cleaned
+		}
+	}
+	return p
+}
+
+func (c *Canonicalizer) query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	params := strings.Split(raw, "&")
+	kept := params[:0]
+	for _, p := range params {
+		if p == "" {
+			continue
+		}
+		name := p
+		if i := strings.IndexByte(p, '='); i != -1 {
+			name = p[:i]
+		}
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		name = strings.ToLower(name)
+		if c.dropParams[name] {
+			continue
+		}
+		if c.dropSessions && (sessionParams[name] || strings.HasPrefix(name, "aspsessionid")) {
+			continue
+		}
+		if c.fixEncoding {
+			p = normalizeEscapes(p)
+		}
+		kept = append(kept, p)
+	}
+	if c.sortQuery {
+		sort.Strings(kept)
+	}
+	return strings.Join(kept, "&")
+}
+
+// Lower case the host and remove the default port for the scheme.
+func canonicalHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) ||
+		(scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndexByte(host, ':')]
+	}
+	return host
+}
+
+// Remove ;jsessionid= path parameters, as added by Java servlet containers.
+func stripPathSession(p string) string {
+	const param = ";jsessionid="
+	for {
+		i := strings.Index(strings.ToLower(p), param)
+		if i == -1 {
+			return p
+		}
+		end := strings.IndexByte(p[i+1:], '/')
+		if end == -1 {
+			p = p[:i]
+		} else {
+			p = p[:i] + p[i+1+end:]
+		}
+	}
+}
+
+// Decode escaped unreserved characters and upper case the remaining escapes.
+func normalizeEscapes(s string) string {
+	if strings.IndexByte(s, '%') == -1 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		v := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(v) {
+			b.WriteByte(v)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
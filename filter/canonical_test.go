@@ -0,0 +1,108 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalizer(t *testing.T) {
+	all := []string{"path", "encoding", "case", "query", "session"}
+	cases := []struct {
+		rules []string
+		drop  []string
+		input string
+		key   string
+	}{
+		{nil, nil, "HTTP://Example.COM:80/a#frag", "http://example.com/a"},
+		{nil, nil, "https://example.com:443/a//b", "https://example.com/a//b"},
+		{nil, nil, "/a?b=1&a=2", "/a?b=1&a=2"},
+		{[]string{"path"}, nil, "http://example.com", "http://example.com/"},
+		{[]string{"path"}, nil, "http://example.com/a//./b/../c/", "http://example.com/a/c/"},
+		{[]string{"path"}, nil, "http://example.com/../", "http://example.com/"},
+		{[]string{"encoding"}, nil, "/%7euser/%2fx%3f", "/~user/%2Fx%3F"},
+		{[]string{"encoding", "path"}, nil, "/a/%2e%2E/b", "/b"},
+		{[]string{"case"}, nil, "/Admin/Index.PHP", "/admin/index.php"},
+		{[]string{"encoding", "case"}, nil, "/%41dmin/%2F", "/admin/%2f"},
+		{[]string{"query"}, nil, "/a?b=1&a=2&&a=1", "/a?a=1&a=2&b=1"},
+		{[]string{"session"}, nil, "/a;jsessionid=ABC/b?SID=1&x=2&ASPSESSIONIDQQ=3", "/a/b?x=2"},
+		{[]string{"session"}, nil, "/a;JSESSIONID=ABC", "/a"},
+		{nil, []string{"utm_source", "Ref"}, "/a?utm_source=x&ref=y&id=1", "/a?id=1"},
+		{all, nil, "/A/./B?z=%7e&PHPSESSID=1", "/a/b?z=~"},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.input)
+		if err != nil {
+			t.Fatalf("Unable to parse %s: %v", c.input, err)
+		}
+		canon := NewCanonicalizer(c.rules, c.drop)
+		if key := canon.URL(u); key != c.key {
+			t.Errorf("Canonicalizing %s with %v: expected %s, got %s", c.input, c.rules, c.key, key)
+		}
+	}
+}
+
+func TestCanonicalizer_StringKey(t *testing.T) {
+	canon := NewCanonicalizer([]string{"path", "query"}, nil)
+	u, _ := url.Parse("http://example.com/a/./b?y=1&x=2")
+	tk := &task.Task{URL: u, Host: "vhost.example.com"}
+	want := "http://example.com/a/b?x=2&y=1 (vhost.example.com)"
+	if key := canon.Key(tk); key != want {
+		t.Errorf("Expected key %s, got %s", want, key)
+	}
+	if key := canon.StringKey(tk.String()); key != want {
+		t.Errorf("Expected string key %s, got %s", want, key)
+	}
+	if key := canon.StringKey("http://example.com/%zz"); key != "http://example.com/%zz" {
+		t.Errorf("Expected unparseable key unchanged, got %s", key)
+	}
+}
+
+func TestFilterCanonicalDuplicates(t *testing.T) {
+	paths := []string{
+		"http://example.com/a/b",
+		"http://example.com/a//b",
+		"http://example.com/a/./b",
+		"http://example.com/a/%62",
+		"http://example.com/a/%42",
+		"http://example.com/a/b?sid=1234",
+		"http://example.com/c?y=1&x=2",
+		"http://example.com/c?x=2&y=1",
+		"http://example.com/d/",
+	}
+	src := make(chan *task.Task, len(paths))
+	for _, p := range paths {
+		u, _ := url.Parse(p)
+		src <- task.NewTaskFromURL(u)
+	}
+	close(src)
+	dupes := 0
+	ss := &settings.ScanSettings{Canonicalize: "path,encoding,case,query,session"}
+	filter := NewWorkFilter(ss, func(_ *task.Task) { dupes++ })
+	filter.MarkDone("http://example.com/d/./?sid=9")
+	var got []string
+	for tk := range filter.RunFilter(src) {
+		got = append(got, tk.URL.String())
+	}
+	if len(got) != 2 || got[0] != paths[0] || got[1] != paths[6] {
+		t.Errorf("Expected %s and %s, got %v", paths[0], paths[6], got)
+	}
+	if dupes != 7 {
+		t.Errorf("Expected 7 dupes, got %d", dupes)
+	}
+}
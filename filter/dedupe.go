@@ -0,0 +1,144 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/logging"
+	"hash/fnv"
+	"math"
+)
+
+// A seenSet records the keys of tasks already processed.  Sets are only used
+// from one goroutine.
+type seenSet interface {
+	// Add a key, returning false if it was already present
+	add(key string) bool
+	// Number of keys added
+	len() int64
+	// Approximate memory used, in bytes
+	memory() int64
+}
+
+// Approximate memory per entry of a map[uint64]struct{}, including buckets
+// and load factor
+const hashEntryBytes = 16
+
+// hashSet stores a 64-bit FNV-1a hash of each key.  With 10 million keys, the
+// chance of any two colliding is around 1 in 400,000.
+type hashSet struct {
+	hashes map[uint64]struct{}
+}
+
+func newHashSet() *hashSet {
+	return &hashSet{hashes: make(map[uint64]struct{})}
+}
+
+func (s *hashSet) add(key string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	if _, ok := s.hashes[sum]; ok {
+		return false
+	}
+	s.hashes[sum] = struct{}{}
+	return true
+}
+
+func (s *hashSet) len() int64 {
+	return int64(len(s.hashes))
+}
+
+func (s *hashSet) memory() int64 {
+	return int64(len(s.hashes)) * hashEntryBytes
+}
+
+// bloomSet is a Bloom filter sized for a number of keys and false positive
+// rate.  A false positive causes a new URL to be treated as a duplicate and
+// skipped.  Memory is fixed, but the false positive rate rises once more keys
+// than the capacity are added.
+type bloomSet struct {
+	bits []uint64
+	// Number of bits
+	m uint64
+	// Number of hash functions
+	k        uint64
+	n        int64
+	capacity int64
+}
+
+func newBloomSet(capacity int64, fpRate float64) *bloomSet {
+	if capacity < 1 {
+		capacity = 1
+	}
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	logging.Logf(logging.LogDebug, "Bloom filter with %d bits and %d hashes for %d URLs.", m, k, capacity)
+	return &bloomSet{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// Derive the bit positions from two hashes, each a 64-bit FNV-1a hash passed
+// through a finalizer, as FNV alone does not spread similar keys well enough.
+func (s *bloomSet) add(key string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1 := mix64(sum)
+	h2 := mix64(sum^0x9e3779b97f4a7c15) | 1
+	present := true
+	for i := uint64(0); i < s.k; i++ {
+		bit := (h1 + i*h2) % s.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if s.bits[word]&mask == 0 {
+			present = false
+			s.bits[word] |= mask
+		}
+	}
+	if !present {
+		s.n++
+		if s.n == s.capacity+1 {
+			logging.Logf(logging.LogWarning, "More than %d URLs seen, some new URLs may be skipped as duplicates.", s.capacity)
+		}
+	}
+	return !present
+}
+
+// The MurmurHash3 64-bit finalizer.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func (s *bloomSet) len() int64 {
+	return s.n
+}
+
+func (s *bloomSet) memory() int64 {
+	return int64(len(s.bits)) * 8
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"testing"
+)
+
+func TestHashSet(t *testing.T) {
+	s := newHashSet()
+	for i := 0; i < 100; i++ {
+		if !s.add(fmt.Sprintf("http://localhost/%d", i)) {
+			t.Errorf("Expected key %d to be new.", i)
+		}
+	}
+	if s.add("http://localhost/5") {
+		t.Error("Expected duplicate key to be rejected.")
+	}
+	if s.len() != 100 {
+		t.Errorf("Expected 100 keys, got %d", s.len())
+	}
+	if s.memory() != 100*hashEntryBytes {
+		t.Errorf("Unexpected memory: %d", s.memory())
+	}
+}
+
+func TestBloomSet(t *testing.T) {
+	const n = 10000
+	s := newBloomSet(n, 0.01)
+	added := 0
+	for i := 0; i < n; i++ {
+		if s.add(fmt.Sprintf("http://localhost/a/%d", i)) {
+			added++
+		}
+	}
+	for i := 0; i < n; i++ {
+		if s.add(fmt.Sprintf("http://localhost/a/%d", i)) {
+			t.Fatalf("Expected key %d to be present.", i)
+		}
+	}
+	// New keys are added as they are probed, so probe few enough that the
+	// rate stays close to the one for n keys
+	const probes = n / 10
+	falsePositives := 0
+	for i := 0; i < probes; i++ {
+		if !s.add(fmt.Sprintf("http://localhost/b/%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > probes/40 {
+		t.Errorf("Expected around 1%% false positives, got %d of %d", falsePositives, probes)
+	}
+	if s.len() != int64(added+probes-falsePositives) {
+		t.Errorf("Expected %d keys, got %d", added+probes-falsePositives, s.len())
+	}
+	// About 9.6 bits per key for 1%
+	if mem := s.memory(); mem < 11000 || mem > 13000 {
+		t.Errorf("Unexpected memory for %d keys: %d", n, mem)
+	}
+}
+
+func TestFilterDedupeUsage(t *testing.T) {
+	for _, fpRate := range []float64{0, 0.001} {
+		src := make(chan *task.Task, 3)
+		for _, p := range []string{"/a", "/b", "/a"} {
+			src <- task.NewTaskFromURL(&url.URL{Path: p})
+		}
+		close(src)
+		ss := &settings.ScanSettings{DedupeFPRate: fpRate, DedupeCapacity: 1000}
+		filter := NewWorkFilter(ss, func(*task.Task) {})
+		count := 0
+		for range filter.RunFilter(src) {
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Expected 2 tasks with rate %f, got %d", fpRate, count)
+		}
+		if seen, mem := filter.DedupeUsage(); seen != 2 || mem <= 0 {
+			t.Errorf("Unexpected usage with rate %f: %d seen, %d bytes", fpRate, seen, mem)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/util"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DepthLimit limits how far a scan recurses, both in the number of steps
+// from the seed URL and in the number of path segments below the scope.
+// A limit of 0 means unlimited.
+type DepthLimit struct {
+	// Maximum Task.Depth
+	MaxDepth int
+	// Maximum path segments below the scope URL
+	MaxScopeDepth int
+	scope         []*url.URL
+}
+
+// Create a DepthLimit for the given scope.  Returns nil if there are no
+// limits.
+func NewDepthLimit(maxDepth, maxScopeDepth int, scope []*url.URL) *DepthLimit {
+	if maxDepth <= 0 && maxScopeDepth <= 0 {
+		return nil
+	}
+	return &DepthLimit{
+		MaxDepth:      maxDepth,
+		MaxScopeDepth: maxScopeDepth,
+		scope:         scope,
+	}
+}
+
+// Check if a task is within the limits.
+func (l *DepthLimit) Allowed(t *task.Task) bool {
+	if l == nil {
+		return true
+	}
+	if l.MaxDepth > 0 && t.Depth > l.MaxDepth {
+		return false
+	}
+	if l.MaxScopeDepth > 0 && l.ScopeDepth(t.URL) > l.MaxScopeDepth {
+		return false
+	}
+	return true
+}
+
+// Check if a task may be expanded, that is, whether its children one level
+// deeper would be within the limits.
+func (l *DepthLimit) CanExpand(t *task.Task) bool {
+	if l == nil {
+		return true
+	}
+	if l.MaxDepth > 0 && t.Depth >= l.MaxDepth {
+		return false
+	}
+	if l.MaxScopeDepth > 0 && l.ScopeDepth(t.URL) >= l.MaxScopeDepth {
+		return false
+	}
+	return true
+}
+
+// Get the number of path segments of u below the most specific scope URL
+// containing it, or below the root if no scope contains it.
+func (l *DepthLimit) ScopeDepth(u *url.URL) int {
+	base := "/"
+	for _, s := range l.scope {
+		if util.URLIsSubpath(s, u) && len(s.Path) > len(base) {
+			base = s.Path
+		}
+	}
+	rel := strings.Trim(strings.TrimPrefix(path.Clean("/"+u.Path), path.Clean(base)), "/")
+	if rel == "" {
+		return 0
+	}
+	return len(strings.Split(rel, "/"))
+}
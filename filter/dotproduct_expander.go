@@ -23,11 +23,14 @@ func (dp *DotProductExpander) Expand(inchan <-chan *task.Task) <-chan *task.Task
 	go func() {
 		defer close(outChan)
 		for it := range inchan {
+			// Copy before sending, later stages may modify it
+			base := it.Copy()
+			// Counted first, so the input isn't finished before them
+			dp.adder(base, len(dp.Hostlist))
 			outChan <- it
 			for _, host := range dp.Hostlist {
-				newIt := it.Copy()
+				newIt := base.Copy()
 				newIt.Host = host
-				dp.adder(1)
 				outChan <- newIt
 			}
 		}
@@ -42,18 +42,21 @@ func (e *ExtensionExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 		defer close(outChan)
 		numExtensions := len(e.extensions)
 		for it := range in {
+			// Copy before sending, later stages may modify it
+			base := it.Copy()
+			expand := !hasExtension(base.URL) && !isDirectory(base.URL)
+			// Counted first, so the input isn't finished before them
+			if expand {
+				e.adder(base, numExtensions)
+			}
 			// Un modified form
 			outChan <- it
-			if hasExtension(it.URL) {
-				continue
-			}
-			if isDirectory(it.URL) {
+			if !expand {
 				continue
 			}
-			e.adder(numExtensions)
 			for _, ext := range e.extensions {
-				t := it.Copy()
-				t.URL.Path = fmt.Sprintf("%s.%s", it.URL.Path, ext)
+				t := base.Copy()
+				t.URL.Path = fmt.Sprintf("%s.%s", base.URL.Path, ext)
 				outChan <- t
 			}
 		}
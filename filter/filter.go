@@ -21,27 +21,56 @@ import (
 	"github.com/Matir/webborer/client"
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/robots"
+	"github.com/Matir/webborer/scope"
 	ss "github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"github.com/Matir/webborer/util"
 	"github.com/Matir/webborer/workqueue"
 	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // WorkFilter is responsible for making sure that a given URL is only tested
 // once, and also for applying any exclusion rules to prevent URLs from being
 // scanned.
 type WorkFilter struct {
-	done     map[string]bool
+	// Keys of tasks already seen
+	done seenSet
+	// Builds the keys in done
+	canon    *Canonicalizer
 	settings *ss.ScanSettings
 	// Excluded paths
 	exclusions []*url.URL
 	// Count the work that has been dropped
 	counter workqueue.QueueDoneFunc
+	// Limit on recursion, may be nil
+	depthLimit *DepthLimit
+	// URLs that may be requested, nil for any
+	scope *scope.Scope
+	// Robots.txt rules by scheme and host
+	robots map[string]*robots.RobotsData
+	// Robots.txt Crawl-delay by host
+	crawlDelays map[string]time.Duration
+	// Size of done, for reading while the filter runs
+	seen       int64
+	seenMemory int64
 }
 
 func NewWorkFilter(settings *ss.ScanSettings, counter workqueue.QueueDoneFunc) *WorkFilter {
-	wf := &WorkFilter{done: make(map[string]bool), settings: settings, counter: counter}
+	wf := &WorkFilter{
+		settings:    settings,
+		counter:     counter,
+		canon:       NewCanonicalizer(strings.Split(settings.Canonicalize, ","), settings.DropParams),
+		robots:      make(map[string]*robots.RobotsData),
+		crawlDelays: make(map[string]time.Duration),
+	}
+	if settings.DedupeFPRate > 0 {
+		wf.done = newBloomSet(settings.DedupeCapacity, settings.DedupeFPRate)
+	} else {
+		wf.done = newHashSet()
+	}
 	wf.exclusions = make([]*url.URL, 0, len(settings.ExcludePaths))
 	for _, path := range settings.ExcludePaths {
 		if u, err := url.Parse(path); err != nil {
@@ -59,21 +88,35 @@ func (f *WorkFilter) RunFilter(src <-chan *task.Task) <-chan *task.Task {
 	go func() {
 	taskLoop:
 		for t := range src {
-			// Fragment is irrelevant for requests to server
-			t.URL.Fragment = ""
-			// TODO: make a more efficient ID function?
-			taskURL := t.String()
-			if _, ok := f.done[taskURL]; ok {
+			// Fragment is irrelevant for requests to server.  Tasks may be
+			// shared with other stages, so only write when needed.
+			if t.URL.Fragment != "" {
+				t.URL.Fragment = ""
+			}
+			// Tasks rejected here are not marked done, as they may be
+			// allowed when found another way
+			if !f.depthLimit.Allowed(t) {
+				f.reject(t, "too deep")
+				continue
+			}
+			if f.scope != nil && !f.scope.Contains(t.URL) {
+				f.reject(t, "out of scope")
+				continue
+			}
+			if !f.markDone(f.canon.Key(t)) {
 				f.reject(t, "already done")
 				continue
 			}
-			f.done[taskURL] = true
 			for _, exclusion := range f.exclusions {
 				if util.URLIsSubpath(exclusion, t.URL) {
 					f.reject(t, "excluded")
 					continue taskLoop
 				}
 			}
+			if !f.robotsAllowed(t.URL) {
+				f.reject(t, "disallowed by robots.txt")
+				continue
+			}
 			c <- t
 		}
 		close(c)
@@ -86,26 +129,99 @@ func (f *WorkFilter) FilterURL(u *url.URL) {
 	f.exclusions = append(f.exclusions, u)
 }
 
-// Filter data from robots.txt
+// Reject tasks beyond the depth limit.
+func (f *WorkFilter) SetDepthLimit(limit *DepthLimit) {
+	f.depthLimit = limit
+}
+
+// Reject tasks outside the scope.  Expansions of tasks in scope may fall
+// outside it when the scope has exclusions.
+func (f *WorkFilter) SetScope(s *scope.Scope) {
+	f.scope = s
+}
+
+// Mark tasks as already done, for example when resuming a scan.  Keys are in
+// the form of Task.String().  Must be called before RunFilter.
+func (f *WorkFilter) MarkDone(keys ...string) {
+	for _, k := range keys {
+		f.markDone(f.canon.StringKey(k))
+	}
+}
+
+// Add a key to the done set, returning false if it was already there.
+func (f *WorkFilter) markDone(key string) bool {
+	if !f.done.add(key) {
+		return false
+	}
+	atomic.StoreInt64(&f.seen, f.done.len())
+	atomic.StoreInt64(&f.seenMemory, f.done.memory())
+	return true
+}
+
+// Number of distinct tasks seen and the approximate memory used to remember
+// them, in bytes.  Safe to call while the filter runs.
+func (f *WorkFilter) DedupeUsage() (seen, memory int64) {
+	return atomic.LoadInt64(&f.seen), atomic.LoadInt64(&f.seenMemory)
+}
+
+// Filter data from robots.txt.  Robots.txt is loaded once for each site in
+// the scope, and applies to all URLs on that site.  Sites whose robots.txt
+// can't be loaded are disallowed entirely.  Must be called before RunFilter.
 func (f *WorkFilter) AddRobotsFilter(scope []*url.URL, clientFactory client.ClientFactory) {
 	for _, scopeURL := range scope {
+		key := robotsKey(scopeURL)
+		if _, ok := f.robots[key]; ok {
+			continue
+		}
 		logging.Logf(logging.LogDebug, "Getting robots.txt exclusions for %s", scopeURL)
 		robotsData, err := robots.GetRobotsForURL(scopeURL, clientFactory)
 		if err != nil {
-			logging.Logf(logging.LogWarning, "Unable to get robots.txt data: %s", err)
-		} else {
-			for _, disallowed := range robotsData.GetForUserAgent(f.settings.UserAgent) {
-				disallowedURL := *scopeURL
-				disallowedURL.Path = disallowed
-				logging.Logf(logging.LogDebug, "Disallowing URL by robots: %s", &disallowedURL)
-				f.FilterURL(&disallowedURL)
+			logging.Logf(logging.LogWarning, "Unable to get robots.txt data, disallowing all of %s: %s", key, err)
+			robotsData = robots.DisallowAll()
+		}
+		for _, rule := range robotsData.GetRulesForUserAgent(f.settings.UserAgent) {
+			logging.Logf(logging.LogDebug, "Robots rule for %s: %s", key, rule)
+		}
+		f.robots[key] = robotsData
+		if delay := robotsData.GetCrawlDelay(f.settings.UserAgent); delay > 0 {
+			host := strings.ToLower(scopeURL.Host)
+			if delay > f.crawlDelays[host] {
+				logging.Logf(logging.LogInfo, "Using robots.txt Crawl-delay of %s for %s", delay, host)
+				f.crawlDelays[host] = delay
 			}
 		}
 	}
 }
 
+// Get the robots.txt Crawl-delay for each host that has one.
+func (f *WorkFilter) CrawlDelays() map[string]time.Duration {
+	return f.crawlDelays
+}
+
+// Check a URL against the robots.txt rules for its site.  Sites without
+// robots.txt data are allowed.
+func (f *WorkFilter) robotsAllowed(u *url.URL) bool {
+	data, ok := f.robots[robotsKey(u)]
+	if !ok {
+		return true
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return data.Allowed(f.settings.UserAgent, path)
+}
+
+// Robots.txt applies to a single scheme, host and port.
+func robotsKey(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
 // Task that can't be used, but should be counted as terminated.
 func (f *WorkFilter) reject(u *task.Task, reason string) {
 	logging.Logf(logging.LogDebug, "Filter rejected %s: %s.", u.String(), reason)
-	f.counter(1)
+	f.counter(u)
 }
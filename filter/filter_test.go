@@ -16,6 +16,7 @@ package filter
 
 import (
 	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/scope"
 	"github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
 	"net/url"
@@ -28,7 +29,7 @@ func TestFilterDuplicates(t *testing.T) {
 		src <- task.NewTaskFromURL(&url.URL{Path: p})
 	}
 	dupes := 0
-	dupefunc := func(i int) { dupes += i }
+	dupefunc := func(_ *task.Task) { dupes++ }
 	filter := NewWorkFilter(&settings.ScanSettings{}, dupefunc)
 	close(src)
 	out := filter.RunFilter(src)
@@ -53,7 +54,7 @@ func TestFilterExclusion(t *testing.T) {
 	src := make(chan *task.Task, 5)
 	src <- task.NewTaskFromURL(&url.URL{Path: "/a"})
 	src <- task.NewTaskFromURL(&url.URL{Path: "/b"})
-	dupefunc := func(_ int) {}
+	dupefunc := func(_ *task.Task) {}
 	ss := &settings.ScanSettings{
 		ExcludePaths: []string{
 			"/a",
@@ -80,29 +81,152 @@ func TestFilterParseFail(t *testing.T) {
 			"://",
 		},
 	}
-	wf := NewWorkFilter(ss, func(_ int) {})
+	wf := NewWorkFilter(ss, func(_ *task.Task) {})
 	if len(wf.exclusions) != 0 {
 		t.Error("Expected error parsing exclusion, but got none.")
 	}
 }
 
 func TestRobotsFilter_Success(t *testing.T) {
-	wf := NewWorkFilter(&settings.ScanSettings{}, func(_ int) {})
+	wf := NewWorkFilter(&settings.ScanSettings{}, func(_ *task.Task) {})
 	client := &mock.MockClient{NextResponse: mock.MockRobotsResponse()}
 	cf := &mock.MockClientFactory{NextClient: client}
 	u, _ := url.Parse("http://localhost/")
 	wf.AddRobotsFilter([]*url.URL{u}, cf)
-	if len(wf.exclusions) != 1 {
-		t.Errorf("Expected one exclusion, got %d", len(wf.exclusions))
+	if len(wf.robots) != 1 {
+		t.Errorf("Expected robots for one site, got %d", len(wf.robots))
+	}
+	for path, allowed := range map[string]bool{"/a": false, "/a/b": false, "/b": true} {
+		if got := wf.robotsAllowed(u.ResolveReference(&url.URL{Path: path})); got != allowed {
+			t.Errorf("Robots allowed %s: expected %v, got %v", path, allowed, got)
+		}
+	}
+	if !wf.robotsAllowed(&url.URL{Scheme: "https", Host: "localhost", Path: "/a"}) {
+		t.Error("Expected robots.txt not to apply to another scheme.")
 	}
 }
 
 func TestRobotsFilter_Fail(t *testing.T) {
-	wf := NewWorkFilter(&settings.ScanSettings{}, func(_ int) {})
+	wf := NewWorkFilter(&settings.ScanSettings{}, func(_ *task.Task) {})
 	cf := &mock.MockClientFactory{}
 	u, _ := url.Parse("http://localhost/")
 	wf.AddRobotsFilter([]*url.URL{u}, cf)
 	if len(wf.exclusions) != 0 {
 		t.Errorf("Expected no exclusions, got %d", len(wf.exclusions))
 	}
+	// An unreachable robots.txt disallows the whole site
+	for _, path := range []string{"/", "/a"} {
+		if wf.robotsAllowed(u.ResolveReference(&url.URL{Path: path})) {
+			t.Errorf("Expected %s to be disallowed.", path)
+		}
+	}
+	if !wf.robotsAllowed(&url.URL{Scheme: "http", Host: "other", Path: "/a"}) {
+		t.Error("Expected other sites to be allowed.")
+	}
+}
+
+func TestFilterMarkDone(t *testing.T) {
+	src := make(chan *task.Task, 3)
+	for _, p := range []string{"/a", "/b", "/c"} {
+		src <- task.NewTaskFromURL(&url.URL{Path: p})
+	}
+	close(src)
+	rejected := 0
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(_ *task.Task) { rejected++ })
+	filter.MarkDone("/a", "/c")
+	out := filter.RunFilter(src)
+	if u, ok := <-out; !ok || u.URL.Path != "/b" {
+		t.Errorf("Expected /b, got %v", u)
+	}
+	if u, ok := <-out; ok {
+		t.Errorf("Expected closed channel, got %v", u)
+	}
+	if rejected != 2 {
+		t.Errorf("Expected 2 rejected, got %d", rejected)
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	if NewDepthLimit(0, 0, nil) != nil {
+		t.Error("Expected no limit when both limits are 0.")
+	}
+	scope := []*url.URL{
+		&url.URL{Scheme: "http", Host: "localhost", Path: "/"},
+		&url.URL{Scheme: "http", Host: "localhost", Path: "/app/"},
+	}
+	limit := NewDepthLimit(3, 2, scope)
+	cases := []struct {
+		path      string
+		depth     int
+		scope     int
+		allowed   bool
+		canExpand bool
+	}{
+		{"/", 0, 0, true, true},
+		{"/a", 1, 1, true, true},
+		{"/a/b/", 2, 2, true, false},
+		{"/a/b/c", 3, 3, false, false},
+		{"/app/x/y", 3, 2, true, false},
+		{"/app/x", 4, 1, false, false},
+	}
+	for _, c := range cases {
+		tk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: c.path})
+		tk.Depth = c.depth
+		if got := limit.ScopeDepth(tk.URL); got != c.scope {
+			t.Errorf("Expected scope depth %d for %s, got %d", c.scope, c.path, got)
+		}
+		if got := limit.Allowed(tk); got != c.allowed {
+			t.Errorf("Expected Allowed(%s, %d) = %v", c.path, c.depth, c.allowed)
+		}
+		if got := limit.CanExpand(tk); got != c.canExpand {
+			t.Errorf("Expected CanExpand(%s, %d) = %v", c.path, c.depth, c.canExpand)
+		}
+	}
+}
+
+func TestFilterDepthLimit(t *testing.T) {
+	src := make(chan *task.Task, 2)
+	src <- &task.Task{URL: &url.URL{Path: "/a"}, Depth: 1}
+	src <- &task.Task{URL: &url.URL{Path: "/b"}, Depth: 2}
+	close(src)
+	rejected := 0
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(_ *task.Task) { rejected++ })
+	filter.SetDepthLimit(NewDepthLimit(1, 0, nil))
+	out := filter.RunFilter(src)
+	if u, ok := <-out; !ok || u.URL.Path != "/a" {
+		t.Errorf("Expected /a, got %v", u)
+	}
+	if u, ok := <-out; ok {
+		t.Errorf("Expected closed channel, got %v", u)
+	}
+	if rejected != 1 {
+		t.Errorf("Expected 1 rejected, got %d", rejected)
+	}
+}
+
+func TestFilterScope(t *testing.T) {
+	src := make(chan *task.Task, 2)
+	src <- &task.Task{URL: &url.URL{Scheme: "http", Host: "www.example.com", Path: "/a"}}
+	src <- &task.Task{URL: &url.URL{Scheme: "http", Host: "sso.example.com", Path: "/a"}}
+	close(src)
+	rejected := 0
+	filter := NewWorkFilter(&settings.ScanSettings{}, func(_ *task.Task) { rejected++ })
+	s := scope.New()
+	if err := s.AddRules(true, "*.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddRules(false, "sso.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	filter.SetScope(s)
+	out := filter.RunFilter(src)
+	if u, ok := <-out; !ok || u.URL.Host != "www.example.com" {
+		t.Errorf("Expected www.example.com, got %v", u)
+	}
+	if u, ok := <-out; ok {
+		t.Errorf("Expected closed channel, got %v", u)
+	}
+	if rejected != 1 {
+		t.Errorf("Expected 1 rejected, got %d", rejected)
+	}
 }
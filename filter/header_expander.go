@@ -41,13 +41,18 @@ func (e *HeaderExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	go func() {
 		defer close(outChan)
 		for it := range in {
+			// Copy before sending, later stages may modify it
+			base := it.Copy()
+			// Counted first, so the input isn't finished before them
+			if n := e.count(); n > 0 {
+				e.adder(base, n)
+			}
 			// Un modified form
 			outChan <- it
 			for k, vals := range e.Header {
 				for _, v := range vals {
-					newIt := it.Copy()
+					newIt := base.Copy()
 					newIt.Header.Set(k, v)
-					e.adder(1)
 					outChan <- newIt
 				}
 			}
@@ -55,3 +60,12 @@ func (e *HeaderExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	}()
 	return outChan
 }
+
+// Number of tasks expanded from each input.
+func (e *HeaderExpander) count() int {
+	n := 0
+	for _, vals := range e.Header {
+		n += len(vals)
+	}
+	return n
+}
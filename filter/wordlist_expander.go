@@ -33,6 +33,10 @@ type WordlistExpander struct {
 	addSlashes bool
 	// Whether to mangle cases
 	mangleCases bool
+	// Limit on recursion, may be nil
+	depthLimit *DepthLimit
+	// Called with each task before it is expanded, may be nil
+	onExpand func(*task.Task)
 }
 
 // A WordMangler is responsible for modifying a wordlist entry to produce
@@ -81,28 +85,155 @@ func (e *WordlistExpander) ProcessWordlist() {
 	e.Wordlist = util.DedupeStrings(newList)
 }
 
+// Maximum number of tasks being expanded at once
+const maxExpansions = 64
+
+// Expand each input task into itself followed by a task for each word.
+// Tasks for the same host are expanded one after another, but expansions for
+// different hosts are interleaved, so that a large wordlist for one host does
+// not hold up the others.
 func (e *WordlistExpander) Expand(in <-chan *task.Task) <-chan *task.Task {
 	out := make(chan *task.Task, cap(in))
 	go func() {
-		for it := range in {
-			out <- it
-			e.adder(len(e.Wordlist))
-			for _, word := range e.Wordlist {
-				t := it.Copy()
-				t.URL = ExtendURL(t.URL, word)
-				out <- t
+		defer close(out)
+		pending := newExpansionQueue()
+		for {
+			if pending.len() == 0 {
+				if in == nil {
+					return
+				}
+				it, ok := <-in
+				if !ok {
+					return
+				}
+				pending.push(e.newExpansion(it))
+				continue
 			}
+			if in != nil && pending.len() < maxExpansions {
+				select {
+				case it, ok := <-in:
+					if !ok {
+						in = nil
+					} else {
+						pending.push(e.newExpansion(it))
+					}
+					continue
+				default:
+				}
+			}
+			out <- pending.next(e.Wordlist)
 		}
-		close(out)
 	}()
 
 	return out
 }
 
+// An expansion in progress.
+type expansion struct {
+	// The input task, sent first
+	task *task.Task
+	// Copy to build children from, as later stages may modify task
+	base *task.Task
+	// Index of the next word, -1 before the task itself is sent
+	word int
+	// Number of words to expand
+	words int
+}
+
+func (e *WordlistExpander) newExpansion(it *task.Task) *expansion {
+	x := &expansion{
+		task: it,
+		base: it.Copy(),
+		word: -1,
+	}
+	if e.depthLimit.CanExpand(x.base) {
+		if e.onExpand != nil {
+			e.onExpand(x.base)
+		}
+		e.adder(x.base, len(e.Wordlist))
+		x.words = len(e.Wordlist)
+	}
+	return x
+}
+
+// expansionQueue holds the expansions in progress for each host, serving the
+// hosts in turn.
+type expansionQueue struct {
+	hosts map[string][]*expansion
+	// Hosts with expansions, in turn order
+	ring []string
+	// Position in ring
+	pos   int
+	count int
+}
+
+func newExpansionQueue() *expansionQueue {
+	return &expansionQueue{hosts: make(map[string][]*expansion)}
+}
+
+func (q *expansionQueue) len() int {
+	return q.count
+}
+
+func (q *expansionQueue) push(x *expansion) {
+	host := strings.ToLower(x.base.URL.Host)
+	if len(q.hosts[host]) == 0 {
+		q.ring = append(q.ring, host)
+	}
+	q.hosts[host] = append(q.hosts[host], x)
+	q.count++
+}
+
+// Get the next task from the current host's first expansion, then move on to
+// the next host.
+func (q *expansionQueue) next(wordlist []string) *task.Task {
+	host := q.ring[q.pos]
+	x := q.hosts[host][0]
+	var t *task.Task
+	if x.word == -1 {
+		t = x.task
+	} else {
+		t = x.base.Copy()
+		t.URL = ExtendURL(t.URL, wordlist[x.word])
+		t.Depth++
+		t.Source = task.SourceWordlist
+		t.Rank = x.word
+	}
+	x.word++
+	if x.word < x.words {
+		q.pos++
+	} else {
+		// Expansion finished
+		q.count--
+		if rest := q.hosts[host][1:]; len(rest) > 0 {
+			q.hosts[host] = rest
+			q.pos++
+		} else {
+			delete(q.hosts, host)
+			q.ring = append(q.ring[:q.pos], q.ring[q.pos+1:]...)
+		}
+	}
+	if q.pos >= len(q.ring) {
+		q.pos = 0
+	}
+	return t
+}
+
 func (e *WordlistExpander) SetAddCount(adder workqueue.QueueAddCount) {
 	e.adder = adder
 }
 
+// Limit the depth of expansion.  Tasks at the limit are passed through
+// without being expanded.
+func (e *WordlistExpander) SetDepthLimit(limit *DepthLimit) {
+	e.depthLimit = limit
+}
+
+// Call f with each task before the wordlist is expanded in it.
+func (e *WordlistExpander) OnExpand(f func(*task.Task)) {
+	e.onExpand = f
+}
+
 func ExtendURL(u *url.URL, tail string) *url.URL {
 	extended := *u
 	if !util.URLIsDir(u) {
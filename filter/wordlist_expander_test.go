@@ -37,7 +37,7 @@ func TestProcessWordlist(t *testing.T) {
 
 func TestExpand(t *testing.T) {
 	wl := []string{"a", "b"}
-	expander := &WordlistExpander{Wordlist: wl, adder: func(_ int) {}}
+	expander := &WordlistExpander{Wordlist: wl, adder: func(_ *task.Task, _ int) {}}
 	ch := make(chan *task.Task, 5)
 	paths := []string{"/foo", "/bar/"}
 	expected := []string{"/foo", "/foo/a", "/foo/b", "/bar/", "/bar/a", "/bar/b"}
@@ -59,3 +59,60 @@ func TestExpand(t *testing.T) {
 		t.Errorf("Expected closed channel, read an item!")
 	}
 }
+
+func TestExpand_DepthLimit(t *testing.T) {
+	wl := []string{"a", "b"}
+	expander := &WordlistExpander{Wordlist: wl, adder: func(_ *task.Task, _ int) {}}
+	expander.SetDepthLimit(NewDepthLimit(2, 0, nil))
+	var onExpand []string
+	expander.OnExpand(func(t *task.Task) {
+		onExpand = append(onExpand, t.URL.Path)
+	})
+	ch := make(chan *task.Task, 5)
+	ch <- &task.Task{URL: &url.URL{Path: "/foo/"}, Depth: 1}
+	ch <- &task.Task{URL: &url.URL{Path: "/bar/"}, Depth: 2}
+	close(ch)
+	res := expander.Expand(ch)
+	expected := []string{"/foo/", "/foo/a", "/foo/b", "/bar/"}
+	expectedDepth := []int{1, 2, 2, 2}
+	for i, exp := range expected {
+		item, ok := <-res
+		if !ok {
+			t.Fatal("Expected an item, got closed channel!")
+		}
+		if exp != item.URL.Path || item.Depth != expectedDepth[i] {
+			t.Errorf("Expected %s at depth %d, got %s at %d.", exp, expectedDepth[i], item.URL.Path, item.Depth)
+		}
+	}
+	if _, ok := <-res; ok {
+		t.Errorf("Expected closed channel, read an item!")
+	}
+	if len(onExpand) != 1 || onExpand[0] != "/foo/" {
+		t.Errorf("Expected only /foo/ to be expanded, got %v", onExpand)
+	}
+}
+
+func TestExpand_InterleaveHosts(t *testing.T) {
+	wl := []string{"a", "b"}
+	expander := &WordlistExpander{Wordlist: wl, adder: func(_ *task.Task, _ int) {}}
+	ch := make(chan *task.Task, 5)
+	ch <- &task.Task{URL: &url.URL{Host: "one", Path: "/"}}
+	ch <- &task.Task{URL: &url.URL{Host: "one", Path: "/x/"}}
+	ch <- &task.Task{URL: &url.URL{Host: "two", Path: "/"}}
+	close(ch)
+	var got []string
+	for item := range expander.Expand(ch) {
+		got = append(got, item.URL.Host+item.URL.Path)
+	}
+	// Expansions for one host stay in order, but alternate with the other host
+	expected := []string{"one/", "two/", "one/a", "two/a", "one/b", "two/b", "one/x/", "one/x/a", "one/x/b"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+			break
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/Matir/webborer/logging"
+	"os"
+	"os/signal"
+)
+
+// Exit code used when a scan is interrupted, as for a shell killed by SIGINT.
+const exitInterrupted = 130
+
+var errInterrupted = errors.New("Scan interrupted.")
+
+// Get a context that is cancelled on the first interrupt, so that the scan
+// stops taking new work and flushes its results.  A second interrupt exits
+// immediately.  The returned function stops handling interrupts.
+func interruptContext(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt)
+	stopped := make(chan bool)
+	go func() {
+		select {
+		case <-sigChan:
+		case <-stopped:
+			return
+		}
+		logging.Logf(logging.LogWarning, "Interrupted, finishing in-flight requests.  Interrupt again to exit immediately.")
+		cancel()
+		select {
+		case <-sigChan:
+			logging.Logf(logging.LogWarning, "Interrupted again, exiting.")
+			os.Exit(exitInterrupted)
+		case <-stopped:
+		}
+	}()
+	stop := func() {
+		signal.Stop(sigChan)
+		close(stopped)
+		cancel()
+	}
+	return ctx, stop
+}
@@ -16,19 +16,35 @@
 package main
 
 import (
-	"github.com/Matir/webborer/workqueue"
+	"github.com/Matir/webborer/stats"
 	"gopkg.in/cheggaaa/pb.v1"
+	"sync"
+	"time"
 )
 
-func initProgressBar(wc *workqueue.WorkCounter) {
+// How often to refresh the statistics on the progress bar
+const progressStatsInterval = 250 * time.Millisecond
+
+// Start a progress bar showing the scan statistics, returning a callback to
+// update it and a function to finish it.
+func initProgressBar(st *stats.Stats) (func(done, total int64), func()) {
 	bar := pb.New(1)
 	bar.ManualUpdate = true
 	bar.ShowTimeLeft = false
+	var mu sync.Mutex
+	var lastStats time.Time
 	var callback = func(done, total int64) {
+		// Statistics change slowly, don't recompute them on every update
+		mu.Lock()
+		if now := time.Now(); now.Sub(lastStats) >= progressStatsInterval {
+			lastStats = now
+			bar.Postfix(" " + st.Snapshot().String())
+		}
+		mu.Unlock()
 		bar.Total = total
 		bar.Set64(done)
 		bar.Update()
 	}
-	wc.SetStatusCallback(callback)
 	bar.Start()
+	return callback, bar.Finish
 }
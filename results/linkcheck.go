@@ -102,7 +102,7 @@ func (rm *LinkCheckResultsManager) linkIsBroken(url string) bool {
 		rm.missing++
 		return false
 	} else {
-		return codeIsBroken(r.Code)
+		return r.Error != nil || codeIsBroken(r.Code)
 	}
 }
 
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 )
 
 // Types of links
@@ -57,8 +58,18 @@ type Result struct {
 	Error error
 	// Redirect URL
 	Redir *url.URL
-	// Content length
+	// Length of the body, or the Content-Length header if longer
 	Length int64
+	// Words and lines in the body
+	Words int64
+	Lines int64
+	// SHA-256 of the body, in hex
+	Hash string
+	// Body was longer than was read, so the words, lines and hash are of the
+	// start of it
+	Truncated bool
+	// Time from sending the request to reading the body
+	Duration time.Duration
 	// Content-type header
 	ContentType string
 	// Known Headers
@@ -69,6 +80,10 @@ type Result struct {
 	ResultGroup string
 	// Links contained in result
 	Links map[string]LinkType
+	// Number of times the request was retried
+	Retries int
+	// Response matched the server's response for nonexistent paths
+	Soft404 bool
 }
 
 // Create a new result.
@@ -128,7 +143,7 @@ type baseResultsManager struct {
 }
 
 // Available output formats as strings.
-var OutputFormats = []string{"text", "csv", "html", "diff"}
+var OutputFormats = []string{"text", "csv", "html", "diff", "json"}
 
 func init() {
 	ss.SetOutputFormats(OutputFormats)
@@ -146,7 +161,7 @@ func FoundSomething(code int) bool {
 
 // Returns true if this result should be included in reports
 func ReportResult(res *Result) bool {
-	return res.Error == nil && FoundSomething(res.Code)
+	return res.Error != nil || (!res.Soft404 && FoundSomething(res.Code))
 }
 
 // Construct a ResultsManager for the given settings in the ss.ScanSettings.
@@ -167,8 +182,19 @@ func GetResultsManager(settings *ss.ScanSettings) (ResultsManager, error) {
 		}
 	}
 
+	var baseURL string
+	if len(settings.BaseURLs) > 0 {
+		// TODO: do more than the first BaseURL
+		baseURL = settings.BaseURLs[0]
+	}
+
+	// Raw results can be turned into any report later.
+	if format == "json" {
+		return &JSONResultsManager{writer: writer, fp: fp}, nil
+	}
+
 	if settings.RunMode == ss.RunModeLinkCheck {
-		rm := &LinkCheckResultsManager{writer: writer, fp: fp, format: format, baseURL: settings.BaseURLs[0]}
+		rm := &LinkCheckResultsManager{writer: writer, fp: fp, format: format, baseURL: baseURL}
 		if err := rm.init(); err != nil {
 			return nil, err
 		}
@@ -181,8 +207,7 @@ func GetResultsManager(settings *ss.ScanSettings) (ResultsManager, error) {
 	case format == "csv":
 		return &CSVResultsManager{writer: csv.NewWriter(writer), fp: fp}, nil
 	case format == "html":
-		// TODO: do more than the first BaseURL
-		return &HTMLResultsManager{writer: writer, fp: fp, BaseURL: settings.BaseURLs[0]}, nil
+		return &HTMLResultsManager{writer: writer, fp: fp, BaseURL: baseURL}, nil
 	case format == "diff":
 		GetResultGroup = func(r *Result) string { return r.URL.Host }
 		return NewDiffResultsManager(writer), nil
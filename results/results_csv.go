@@ -40,7 +40,7 @@ func (rm *CSVResultsManager) Run(res <-chan *Result) {
 		}()
 
 		// Header line
-		rm.writer.Write([]string{"code", "url", "content_length", "redirect_url"})
+		rm.writer.Write([]string{"code", "url", "content_length", "redirect_url", "error", "retries", "words", "lines", "hash", "truncated", "duration_ms"})
 
 		for r := range res {
 			rm.runOne(r)
@@ -61,6 +61,13 @@ func (rm *CSVResultsManager) runOne(res *Result) {
 		res.URL.String(),
 		clen,
 		maybeStringURL(res.Redir),
+		maybeStringError(res.Error),
+		fmt.Sprintf("%d", res.Retries),
+		fmt.Sprintf("%d", res.Words),
+		fmt.Sprintf("%d", res.Lines),
+		res.Hash,
+		fmt.Sprintf("%t", res.Truncated),
+		fmt.Sprintf("%d", res.Duration.Milliseconds()),
 	}
 	rm.writer.Write(record)
 }
@@ -71,3 +78,10 @@ func maybeStringURL(u *url.URL) string {
 	}
 	return u.String()
 }
+
+func maybeStringError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -17,8 +17,10 @@ package results
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Long test to thoroughly test CSV writing.
@@ -29,6 +31,14 @@ func TestWriteCSV(t *testing.T) {
 		writer: csv.NewWriter(&buf),
 	}
 	res := makeTestResults()
+	res[0].Length = 12
+	res[0].Words = 3
+	res[0].Lines = 1
+	res[0].Hash = "abc123"
+	res[0].Duration = 35 * time.Millisecond
+	res[0].Truncated = true
+	res[1].Error = errors.New("connection reset")
+	res[1].Retries = 2
 	mgr.Run(rchan)
 	for _, r := range res {
 		rchan <- r
@@ -36,19 +46,23 @@ func TestWriteCSV(t *testing.T) {
 	close(rchan)
 	mgr.Wait()
 	lines := strings.Split(buf.String(), "\n")
-	if len(lines) != 4 {
-		t.Fatalf("Expected 2 lines of output, got %d.", len(lines))
+	if len(lines) != 5 {
+		t.Fatalf("Expected 4 lines of output, got %d.", len(lines))
 	}
-	hdr := "code,url,content_length,redirect_url"
+	hdr := "code,url,content_length,redirect_url,error,retries,words,lines,hash,truncated,duration_ms"
 	if lines[0] != hdr {
 		t.Errorf("Expected header \"%s\", got header \"%s\".", hdr, lines[0])
 	}
-	resStr := "200,http://localhost/,0,"
+	resStr := "200,http://localhost/,12,,,0,3,1,abc123,true,35"
 	if lines[1] != resStr {
 		t.Errorf("Expected result string \"%s\", got result string \"%s\".", resStr, lines[1])
 	}
-	resStr = "301,http://localhost/.git,0,https://localhost/.git"
+	resStr = "404,http://localhost/x,0,,connection reset,2,0,0,,false,0"
 	if lines[2] != resStr {
-		t.Errorf("Expected result string \"%s\", got result string \"%s\".", resStr, lines[1])
+		t.Errorf("Expected result string \"%s\", got result string \"%s\".", resStr, lines[2])
+	}
+	resStr = "301,http://localhost/.git,0,https://localhost/.git,,0,0,0,,false,0"
+	if lines[3] != resStr {
+		t.Errorf("Expected result string \"%s\", got result string \"%s\".", resStr, lines[3])
 	}
 }
@@ -150,7 +150,12 @@ func (drm *DiffResultsManager) WriteResults() error {
 			return err
 		}
 		for _, result := range group {
-			if _, err := fmt.Fprintf(fp, "\t%s\t%s\t%d\n", result.URL.String(), result.Host, result.Code); err != nil {
+			if result.Error != nil {
+				_, err := fmt.Fprintf(fp, "\t%s\t%s\tERR %s%s\n", result.URL.String(), result.Host, result.Error.Error(), retriesSuffix(result))
+				if err != nil {
+					return err
+				}
+			} else if _, err := fmt.Fprintf(fp, "\t%s\t%s\t%d\t%s\n", result.URL.String(), result.Host, result.Code, bodyMetrics(result)); err != nil {
 				return err
 			}
 		}
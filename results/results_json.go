@@ -0,0 +1,195 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/settings"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// JSONResultsManager writes every result, including errors and missing pages,
+// as one JSON object per line, with the values of secret request headers
+// hidden.  The output can be read back with
+// ReadJSONResults to produce any other output format later.
+type JSONResultsManager struct {
+	baseResultsManager
+	writer io.Writer
+	fp     *os.File
+}
+
+// Serialized form of a Result.
+type jsonResult struct {
+	URL            string            `json:"url"`
+	Host           string            `json:"host,omitempty"`
+	Code           int               `json:"code"`
+	Error          string            `json:"error,omitempty"`
+	Retries        int               `json:"retries,omitempty"`
+	Soft404        bool              `json:"soft_404,omitempty"`
+	Redir          string            `json:"redirect,omitempty"`
+	Length         int64             `json:"length"`
+	Words          int64             `json:"words,omitempty"`
+	Lines          int64             `json:"lines,omitempty"`
+	Hash           string            `json:"hash,omitempty"`
+	Truncated      bool              `json:"truncated,omitempty"`
+	DurationMS     float64           `json:"duration_ms,omitempty"`
+	ContentType    string            `json:"content_type,omitempty"`
+	RequestHeader  http.Header       `json:"request_header,omitempty"`
+	ResponseHeader http.Header       `json:"response_header,omitempty"`
+	ResultGroup    string            `json:"group,omitempty"`
+	Links          map[string]string `json:"links,omitempty"`
+}
+
+func (rm *JSONResultsManager) Run(res <-chan *Result) {
+	rm.start()
+	go func() {
+		buf := bufio.NewWriter(rm.writer)
+		enc := json.NewEncoder(buf)
+		defer func() {
+			buf.Flush()
+			if rm.fp != nil {
+				rm.fp.Close()
+			}
+			rm.done()
+		}()
+
+		for r := range res {
+			if err := enc.Encode(r.toJSON()); err != nil {
+				logging.Logf(logging.LogWarning, "Error writing JSON result: %s", err.Error())
+			}
+		}
+	}()
+}
+
+func (r *Result) toJSON() *jsonResult {
+	jr := &jsonResult{
+		URL:            r.URL.String(),
+		Host:           r.Host,
+		Code:           r.Code,
+		Redir:          maybeStringURL(r.Redir),
+		Length:         r.Length,
+		Words:          r.Words,
+		Lines:          r.Lines,
+		Hash:           r.Hash,
+		Truncated:      r.Truncated,
+		DurationMS:     float64(r.Duration) / float64(time.Millisecond),
+		ContentType:    r.ContentType,
+		RequestHeader:  settings.RedactHeader(r.RequestHeader),
+		ResponseHeader: r.ResponseHeader,
+		ResultGroup:    r.ResultGroup,
+		Retries:        r.Retries,
+		Soft404:        r.Soft404,
+	}
+	if r.Error != nil {
+		jr.Error = r.Error.Error()
+	}
+	if len(r.Links) > 0 {
+		jr.Links = make(map[string]string)
+		for k, t := range r.Links {
+			jr.Links[k] = LinkTypes[t]
+		}
+	}
+	return jr
+}
+
+func (jr *jsonResult) toResult() (*Result, error) {
+	u, err := url.Parse(jr.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{
+		URL:            u,
+		Host:           jr.Host,
+		Code:           jr.Code,
+		Length:         jr.Length,
+		Words:          jr.Words,
+		Lines:          jr.Lines,
+		Hash:           jr.Hash,
+		Truncated:      jr.Truncated,
+		Duration:       time.Duration(jr.DurationMS * float64(time.Millisecond)),
+		ContentType:    jr.ContentType,
+		RequestHeader:  jr.RequestHeader,
+		ResponseHeader: jr.ResponseHeader,
+		ResultGroup:    jr.ResultGroup,
+		Retries:        jr.Retries,
+		Soft404:        jr.Soft404,
+	}
+	if jr.Error != "" {
+		r.Error = errors.New(jr.Error)
+	}
+	if jr.Redir != "" {
+		if r.Redir, err = url.Parse(jr.Redir); err != nil {
+			return nil, err
+		}
+	}
+	for k, t := range jr.Links {
+		ltype := LinkUnknown
+		for i, name := range LinkTypes {
+			if name == t {
+				ltype = LinkType(i)
+				break
+			}
+		}
+		if r.Links == nil {
+			r.Links = make(map[string]LinkType)
+		}
+		r.Links[k] = ltype
+	}
+	return r, nil
+}
+
+func (r *Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.toJSON())
+}
+
+func (r *Result) UnmarshalJSON(data []byte) error {
+	jr := &jsonResult{}
+	if err := json.Unmarshal(data, jr); err != nil {
+		return err
+	}
+	res, err := jr.toResult()
+	if err != nil {
+		return err
+	}
+	*r = *res
+	return nil
+}
+
+// Read results written by a JSONResultsManager.
+func ReadJSONResults(rdr io.Reader) ([]*Result, error) {
+	dec := json.NewDecoder(rdr)
+	res := make([]*Result, 0)
+	for {
+		jr := &jsonResult{}
+		if err := dec.Decode(jr); err == io.EOF {
+			return res, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Unable to parse result %d: %s", len(res)+1, err.Error())
+		}
+		r, err := jr.toResult()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse result %d: %s", len(res)+1, err.Error())
+		}
+		res = append(res, r)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJSON_RoundTrip(t *testing.T) {
+	rchan := make(chan *Result)
+	buf := bytes.Buffer{}
+	mgr := JSONResultsManager{writer: &buf}
+	res := makeTestResults()
+	res[0].AddLink(&url.URL{Scheme: "http", Host: "localhost", Path: "/x"}, LinkHREF)
+	res[1].Error = errors.New("connection reset")
+	res[1].Retries = 2
+	res[0].Words = 3
+	res[0].Lines = 1
+	res[0].Hash = "abc123"
+	res[0].Truncated = true
+	res[0].Duration = 1500 * time.Microsecond
+	mgr.Run(rchan)
+	for _, r := range res {
+		rchan <- r
+	}
+	close(rchan)
+	mgr.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(res) {
+		t.Fatalf("Expected %d lines of output, got %d.", len(res), len(lines))
+	}
+
+	loaded, err := ReadJSONResults(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error reading results: %v", err)
+	}
+	if len(loaded) != len(res) {
+		t.Fatalf("Expected %d results, got %d.", len(res), len(loaded))
+	}
+	for i, r := range loaded {
+		if r.String() != res[i].String() {
+			t.Errorf("Result mismatch: %s vs %s", r, res[i])
+		}
+	}
+	if loaded[0].Links["http://localhost/x"] != LinkHREF {
+		t.Errorf("Links not preserved: %v", loaded[0].Links)
+	}
+	if loaded[1].Error == nil || loaded[1].Error.Error() != "connection reset" {
+		t.Errorf("Error not preserved: %v", loaded[1].Error)
+	}
+	if r := loaded[0]; r.Words != 3 || r.Lines != 1 || r.Hash != "abc123" || !r.Truncated || r.Duration != res[0].Duration {
+		t.Errorf("Body metrics not preserved: %+v", r)
+	}
+	if loaded[1].Truncated {
+		t.Error("Expected only the first result to be truncated.")
+	}
+	if loaded[1].Retries != 2 {
+		t.Errorf("Retries not preserved: %d", loaded[1].Retries)
+	}
+	if loaded[2].Redir == nil || loaded[2].Redir.String() != "https://localhost/.git" {
+		t.Errorf("Redirect not preserved: %v", loaded[2].Redir)
+	}
+}
+
+func TestWriteJSON_SecretHeaders(t *testing.T) {
+	r := makeTestResults()[0]
+	r.RequestHeader = http.Header{
+		"Authorization": {"Bearer hunter2"},
+		"Cookie":        {"session=hunter2"},
+		"X-Team":        {"red"},
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Unexpected error encoding result: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected secret headers to be hidden, got %s", data)
+	}
+	loaded := &Result{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("Unexpected error decoding result: %v", err)
+	}
+	if loaded.RequestHeader.Get("X-Team") != "red" || loaded.RequestHeader.Get("Authorization") != "<redacted>" {
+		t.Errorf("Unexpected request headers: %v", loaded.RequestHeader)
+	}
+	if r.RequestHeader.Get("Authorization") != "Bearer hunter2" {
+		t.Error("Expected result headers to be unchanged.")
+	}
+}
+
+func TestReadJSONResults_Invalid(t *testing.T) {
+	if _, err := ReadJSONResults(strings.NewReader("{\"url\": 5}\n")); err == nil {
+		t.Error("Expected error reading invalid results.")
+	}
+}
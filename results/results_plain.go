@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 // PlainResultsManager is designed to output a very basic output that is good
@@ -44,15 +45,43 @@ func (rm *PlainResultsManager) Run(res <-chan *Result) {
 			if !ReportResult(r) {
 				continue
 			}
-			if r.Redir == nil {
-				if r.Length >= 0 {
-					fmt.Fprintf(rm.writer, "%d %s (%d bytes)\n", r.Code, r.URL.String(), r.Length)
-				} else {
-					fmt.Fprintf(rm.writer, "%d %s\n", r.Code, r.URL.String())
-				}
+			if r.Error != nil {
+				fmt.Fprintf(rm.writer, "ERR %s: %s%s\n", r.URL.String(), r.Error.Error(), retriesSuffix(r))
+			} else if r.Redir == nil {
+				fmt.Fprintf(rm.writer, "%d %s (%s)\n", r.Code, r.URL.String(), bodyMetrics(r))
 			} else if rm.redirs {
 				fmt.Fprintf(rm.writer, "%d %s -> %s\n", r.Code, r.URL.String(), r.Redir.String())
 			}
 		}
 	}()
 }
+
+// Describe the body of a result, with a short prefix of its hash.
+func bodyMetrics(r *Result) string {
+	var parts []string
+	if r.Length >= 0 {
+		parts = append(parts, fmt.Sprintf("%d bytes", r.Length))
+	}
+	if r.Hash != "" {
+		parts = append(parts, fmt.Sprintf("%d words", r.Words), fmt.Sprintf("%d lines", r.Lines))
+	}
+	parts = append(parts, fmt.Sprintf("%dms", r.Duration.Milliseconds()))
+	if len(r.Hash) >= 12 {
+		parts = append(parts, "sha256:"+r.Hash[:12])
+	}
+	if r.Truncated {
+		parts = append(parts, "truncated")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func retriesSuffix(r *Result) string {
+	switch r.Retries {
+	case 0:
+		return ""
+	case 1:
+		return " (1 retry)"
+	default:
+		return fmt.Sprintf(" (%d retries)", r.Retries)
+	}
+}
@@ -16,8 +16,10 @@ package results
 
 import (
 	"bytes"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TODO: refactor this test to have a single test runner
@@ -39,3 +41,43 @@ func TestPlainResultsManager_Basic(t *testing.T) {
 		t.Fatalf("Expected 3 lines of output, got %d", len(lines))
 	}
 }
+
+func TestPlainResultsManager_Error(t *testing.T) {
+	buf := bytes.Buffer{}
+	mgr := &PlainResultsManager{writer: &buf}
+	rchan := make(chan *Result)
+	mgr.Run(rchan)
+	r := makeTestResults()[1]
+	r.Error = errors.New("connection reset")
+	r.Retries = 2
+	rchan <- r
+	close(rchan)
+	mgr.Wait()
+	expected := "ERR http://localhost/x: connection reset (2 retries)\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestBodyMetrics(t *testing.T) {
+	r := makeTestResults()[0]
+	r.Length = 1234
+	r.Words = 56
+	r.Lines = 7
+	r.Hash = "0123456789abcdef0123"
+	r.Duration = 35 * time.Millisecond
+	expected := "1234 bytes, 56 words, 7 lines, 35ms, sha256:0123456789ab"
+	if m := bodyMetrics(r); m != expected {
+		t.Errorf("Expected %q, got %q", expected, m)
+	}
+	r.Truncated = true
+	if m := bodyMetrics(r); m != expected+", truncated" {
+		t.Errorf("Expected %q, got %q", expected+", truncated", m)
+	}
+	r.Length = -1
+	r.Hash = ""
+	r.Truncated = false
+	if m := bodyMetrics(r); m != "35ms" {
+		t.Errorf("Expected only duration without a body, got %q", m)
+	}
+}
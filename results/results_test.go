@@ -15,6 +15,7 @@
 package results
 
 import (
+	"errors"
 	"github.com/Matir/webborer/settings"
 	"net/url"
 	"strings"
@@ -73,6 +74,10 @@ func TestReportResult(t *testing.T) {
 	if !ReportResult(r) {
 		t.Error("Expected to report a result of 200.")
 	}
+	r = &Result{Error: errors.New("timeout")}
+	if !ReportResult(r) {
+		t.Error("Expected to report a failed request.")
+	}
 }
 
 func TestBaseFunctions(_ *testing.T) {
@@ -12,23 +12,54 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package robots provides support for parsing robots.txt files.
+// Package robots provides support for parsing robots.txt files, and matching
+// paths against them as described in RFC 9309.
 package robots
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"io"
 	"io/ioutil"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Robots.txt files are only parsed up to this size.
+const maxRobotsSize = 500 * 1024
+
 type RobotsData struct {
 	Groups []RobotsGroup
+	// Sitemap URLs, which apply to all user agents
+	Sitemaps []string
 }
 
 type RobotsGroup struct {
 	UserAgents []string
-	Disallow   []string
+	// Disallowed paths, in order
+	Disallow []string
+	// Allow and Disallow rules, in order
+	Rules []RobotsRule
+	// Minimum time between requests, 0 if not given
+	CrawlDelay time.Duration
+}
+
+// A single Allow or Disallow line.  Paths may contain * to match any
+// sequence of characters, and end in $ to match the end of the URL.
+type RobotsRule struct {
+	Allow bool
+	Path  string
+}
+
+func (r RobotsRule) String() string {
+	if r.Allow {
+		return "Allow: " + r.Path
+	}
+	return "Disallow: " + r.Path
 }
 
 func ParseRobotsTxt(text []byte) (*RobotsData, error) {
@@ -50,6 +81,19 @@ func ParseRobotsTxt(text []byte) (*RobotsData, error) {
 		case "disallow":
 			agents_finished = true
 			curr_group.Disallow = append(curr_group.Disallow, string(value))
+			curr_group.Rules = append(curr_group.Rules, RobotsRule{Path: string(value)})
+		case "allow":
+			agents_finished = true
+			curr_group.Rules = append(curr_group.Rules, RobotsRule{Allow: true, Path: string(value)})
+		case "crawl-delay":
+			agents_finished = true
+			if secs, err := strconv.ParseFloat(string(value), 64); err == nil && secs > 0 {
+				curr_group.CrawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		case "sitemap":
+			if len(value) > 0 {
+				robots.Sitemaps = append(robots.Sitemaps, string(value))
+			}
 		}
 	}
 	if len(curr_group.UserAgents) > 0 {
@@ -79,6 +123,19 @@ func newRobotsGroup() RobotsGroup {
 	}
 }
 
+// Rules that disallow everything, for a site whose robots.txt is unreachable.
+func DisallowAll() *RobotsData {
+	return &RobotsData{Groups: []RobotsGroup{{
+		UserAgents: []string{"*"},
+		Disallow:   []string{"/"},
+		Rules:      []RobotsRule{{Path: "/"}},
+	}}}
+}
+
+// Load robots.txt for the site of target.  A missing robots.txt (any 4xx
+// status) allows everything, while a server error disallows everything.  An
+// error is returned if the server can't be reached, which callers should
+// treat as disallowing everything too.
 func GetRobotsForURL(target *url.URL, factory client.ClientFactory) (*RobotsData, error) {
 	client := factory.Get()
 	ref, _ := url.Parse("/robots.txt")
@@ -87,34 +144,180 @@ func GetRobotsForURL(target *url.URL, factory client.ClientFactory) (*RobotsData
 	if err != nil {
 		return nil, err
 	}
-	body, err := ioutil.ReadAll(resp.Body)
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		logging.Logf(logging.LogDebug, "No robots.txt at %s (%d), allowing all.", robotsURL, resp.StatusCode)
+		return &RobotsData{Groups: make([]RobotsGroup, 0)}, nil
+	case resp.StatusCode >= 500:
+		logging.Logf(logging.LogWarning, "Unable to load %s (%d), disallowing all.", robotsURL, resp.StatusCode)
+		return DisallowAll(), nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
 	if err != nil {
 		return nil, err
 	}
 	return ParseRobotsTxt(body)
 }
 
+// Get the disallowed paths for the given user agent.
 func (data *RobotsData) GetForUserAgent(targetAgent string) []string {
+	var res []string
+	for _, rule := range data.GetRulesForUserAgent(targetAgent) {
+		if !rule.Allow {
+			res = append(res, rule.Path)
+		}
+	}
+	return res
+}
+
+// Get the rules for the given user agent.  The rules of all groups naming
+// the agent are combined, falling back to the groups for '*'.  Agents are
+// matched case-insensitively by product token, so "WebBorer/1.0" matches a
+// group for "webborer".
+func (data *RobotsData) GetRulesForUserAgent(targetAgent string) []RobotsRule {
+	var res []RobotsRule
+	for _, group := range data.groupsForUserAgent(targetAgent) {
+		res = append(res, group.Rules...)
+	}
+	return res
+}
+
+// Get the Crawl-delay for the given user agent, or 0 if there is none.  If
+// several groups apply, the longest delay is used.
+func (data *RobotsData) GetCrawlDelay(targetAgent string) time.Duration {
+	var delay time.Duration
+	for _, group := range data.groupsForUserAgent(targetAgent) {
+		if group.CrawlDelay > delay {
+			delay = group.CrawlDelay
+		}
+	}
+	return delay
+}
+
+// Get the groups that apply to the given user agent.
+func (data *RobotsData) groupsForUserAgent(targetAgent string) []RobotsGroup {
+	token := productToken(targetAgent)
+	var res []RobotsGroup
 	for _, group := range data.Groups {
 		for _, agent := range group.UserAgents {
-			if agent == targetAgent {
-				return group.Disallow
+			if productToken(agent) == token {
+				res = append(res, group)
+				break
 			}
 		}
 	}
-
-	if targetAgent == "*" {
-		return nil
+	if len(res) > 0 || targetAgent == "*" {
+		return res
 	}
 
 	// Fallback to '*'
-	return data.GetForUserAgent("*")
+	return data.groupsForUserAgent("*")
 }
 
+// Get the paths named in any rule, for seeding.  Wildcards are removed and
+// duplicates are skipped.
 func (data *RobotsData) GetAllPaths() []string {
 	results := make([]string, 0)
+	seen := make(map[string]bool)
 	for _, group := range data.Groups {
-		results = append(results, group.Disallow...)
+		for _, rule := range group.Rules {
+			path := strings.TrimSuffix(rule.Path, "$")
+			if i := strings.IndexByte(path, '*'); i != -1 {
+				path = path[:i]
+			}
+			if path == "" || seen[path] {
+				continue
+			}
+			seen[path] = true
+			results = append(results, path)
+		}
 	}
 	return results
 }
+
+// Check whether a path, including any query string, may be fetched by the
+// given user agent.  The rule with the longest matching path decides, and
+// Allow wins ties.  /robots.txt itself is always allowed.
+func (data *RobotsData) Allowed(agent, path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	path = normalizePath(path)
+	allowed := true
+	longest := -1
+	for _, rule := range data.GetRulesForUserAgent(agent) {
+		if rule.Path == "" {
+			continue
+		}
+		pattern := normalizePath(rule.Path)
+		if !matchPattern(pattern, path) {
+			continue
+		}
+		if len(pattern) > longest || (len(pattern) == longest && rule.Allow) {
+			longest = len(pattern)
+			allowed = rule.Allow
+		}
+	}
+	return allowed
+}
+
+// Get the product token from a user agent, such as "webborer" from
+// "WebBorer/1.0 (+https://example.com/)".
+func productToken(agent string) string {
+	if i := strings.IndexAny(agent, "/ \t"); i != -1 {
+		agent = agent[:i]
+	}
+	return strings.ToLower(agent)
+}
+
+// Match a path against a rule pattern, where * matches any sequence of
+// characters and a trailing $ matches the end of the path.  Patterns
+// otherwise match any path they are a prefix of.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	// Leftmost matches of the middle parts leave the most room for the last.
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i == -1 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
+
+// Percent-encode characters outside of US-ASCII and use upper case escapes,
+// so that paths and patterns compare equal however they were written.
+func normalizePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c >= 0x80:
+			fmt.Fprintf(&b, "%%%02X", c)
+		case c == '%' && i+2 < len(path):
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(path[i+1 : i+3]))
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -21,6 +21,7 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 )
 
 func loadTestRobots(t *testing.T) *RobotsData {
@@ -169,3 +170,153 @@ func TestGetAllPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestAllowed(t *testing.T) {
+	parsed := loadTestRobots(t)
+	for _, tc := range []struct {
+		agent   string
+		path    string
+		allowed bool
+	}{
+		{"a", "/a", false},
+		{"a", "/a/x", false},
+		{"a", "/d", true},
+		{"b", "/foo/bar/baz", false},
+		{"b", "/foo", true},
+		{"other", "/anything", false},
+	} {
+		if got := parsed.Allowed(tc.agent, tc.path); got != tc.allowed {
+			t.Errorf("Allowed(%s, %s): expected %v, got %v", tc.agent, tc.path, tc.allowed, got)
+		}
+	}
+}
+
+func TestAllowed_RFC9309(t *testing.T) {
+	text := `User-agent: WebBorer
+Disallow: /private
+Allow: /private/public
+Disallow: /*.php$
+Allow: /page
+Disallow: /page
+Disallow: /fish*.html
+Allow: /shop/*/view
+Disallow: /shop/
+Disallow: /%e3%83%84
+
+User-agent: webborer
+Disallow: /merged
+
+User-agent: *
+Disallow: /
+`
+	parsed, err := ParseRobotsTxt([]byte(text))
+	if err != nil {
+		t.Fatalf("Could not parse robots: %v", err)
+	}
+	for _, tc := range []struct {
+		agent   string
+		path    string
+		allowed bool
+	}{
+		{"WebBorer/1.0", "/private/x", false},
+		{"WebBorer/1.0", "/private/public/x", true},
+		{"webborer", "/index.php", false},
+		{"webborer", "/index.php?x=1", true},
+		{"webborer", "/index.phps", true},
+		{"webborer", "/page", true},
+		{"webborer", "/fish/salmon.html", false},
+		{"webborer", "/fish.htm", true},
+		{"webborer", "/shop/1/view", true},
+		{"webborer", "/shop/1/edit", false},
+		{"webborer", "/ツ", false},
+		{"webborer", "/merged/x", false},
+		{"webborer", "/other", true},
+		{"other", "/other", false},
+		{"other", "/robots.txt", true},
+	} {
+		if got := parsed.Allowed(tc.agent, tc.path); got != tc.allowed {
+			t.Errorf("Allowed(%s, %s): expected %v, got %v", tc.agent, tc.path, tc.allowed, got)
+		}
+	}
+	expected := []string{"/private", "/private/public", "/", "/page", "/fish", "/shop/", "/%e3%83%84", "/merged"}
+	if paths := parsed.GetAllPaths(); len(paths) != len(expected) {
+		t.Errorf("Expected paths %v, got %v", expected, paths)
+	} else {
+		for i := range expected {
+			if paths[i] != expected[i] {
+				t.Errorf("Expected path %s, got %s", expected[i], paths[i])
+			}
+		}
+	}
+}
+
+func TestGetRobotsForURL_Status(t *testing.T) {
+	for _, tc := range []struct {
+		code    int
+		allowed bool
+	}{
+		{404, true},
+		{503, false},
+	} {
+		resp := mock.ResponseFromString("User-agent: *\nAllow: /\n")
+		resp.StatusCode = tc.code
+		factory := &mock.MockClientFactory{
+			ForeverClient: &mock.MockClient{NextResponse: resp},
+		}
+		data, err := GetRobotsForURL(&url.URL{Scheme: "http", Host: "localhost"}, factory)
+		if err != nil {
+			t.Fatalf("Error when loading robots for status %d: %v", tc.code, err)
+		}
+		if got := data.Allowed("webborer", "/x"); got != tc.allowed {
+			t.Errorf("Status %d: expected allowed %v, got %v", tc.code, tc.allowed, got)
+		}
+	}
+}
+
+func TestParseRobots_Sitemaps(t *testing.T) {
+	text := `Sitemap: http://example.com/a.xml
+User-agent: *
+Disallow: /x
+sitemap: /b.xml.gz
+Disallow: /y
+`
+	parsed, err := ParseRobotsTxt([]byte(text))
+	if err != nil {
+		t.Fatalf("Could not parse robots: %v", err)
+	}
+	if len(parsed.Sitemaps) != 2 || parsed.Sitemaps[0] != "http://example.com/a.xml" || parsed.Sitemaps[1] != "/b.xml.gz" {
+		t.Errorf("Unexpected sitemaps: %v", parsed.Sitemaps)
+	}
+	if paths := parsed.GetForUserAgent("any"); len(paths) != 2 {
+		t.Errorf("Expected sitemap lines not to end the group, got %v", paths)
+	}
+}
+
+func TestGetCrawlDelay(t *testing.T) {
+	text := `User-agent: webborer
+Crawl-delay: 2.5
+Disallow: /x
+
+User-agent: webborer
+Crawl-delay: 1
+
+User-agent: other
+Crawl-delay: nonsense
+
+User-agent: *
+Crawl-delay: 10
+`
+	parsed, err := ParseRobotsTxt([]byte(text))
+	if err != nil {
+		t.Fatalf("Could not parse robots: %v", err)
+	}
+	for agent, delay := range map[string]time.Duration{
+		"WebBorer/1.0": 2500 * time.Millisecond,
+		"other":        0,
+		"unknown":      10 * time.Second,
+	} {
+		if got := parsed.GetCrawlDelay(agent); got != delay {
+			t.Errorf("Crawl delay for %s: expected %s, got %s", agent, delay, got)
+		}
+	}
+}
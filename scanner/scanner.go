@@ -0,0 +1,486 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scanner runs webborer scans in-process.
+//
+// A Scanner wires together the workqueue, expanders, filter, workers and
+// results manager for a single scan:
+//
+//	settings := ss.NewCommandSettings("scanner", ss.FlagsAll)
+//	settings.BaseURLs = []string{"http://localhost/"}
+//	s, err := scanner.NewScanner(settings)
+//	if err != nil { ... }
+//	s.OnResult(func(r *results.Result) { ... })
+//	err = s.Run(ctx)
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"github.com/Matir/webborer/checkpoint"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/filter"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/scope"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/stats"
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/throttle"
+	"github.com/Matir/webborer/wordlist"
+	"github.com/Matir/webborer/worker"
+	"github.com/Matir/webborer/workqueue"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// A Scanner runs a single scan based on ScanSettings.
+type Scanner struct {
+	settings       *ss.ScanSettings
+	scope          []*url.URL
+	rules          *scope.Scope
+	clientFactory  client.ClientFactory
+	resultsManager results.ResultsManager
+	resultHook     func(*results.Result)
+	progressHook   func(done, total int64)
+	errorHook      func(*url.URL, error)
+	checkpoint     *checkpoint.Checkpoint
+	summary        Summary
+	stats          *stats.Stats
+	running        bool
+	mu             sync.Mutex
+}
+
+// Summary of a scan once it has run.
+type Summary struct {
+	// Number of HTTP requests made, if the client factory was built from the
+	// settings
+	Requests int64
+	// Number of tasks abandoned because the scan stopped early
+	Unexplored int64
+	// Why the scan stopped early, or empty if it finished
+	StopReason string
+	// Request statistics
+	Stats stats.Snapshot
+	// Distinct URLs seen, and the approximate memory used to remember them
+	URLsSeen     int64
+	DedupeMemory int64
+}
+
+// Reasons for stopping a scan early
+const (
+	StopCancelled     = "cancelled"
+	StopRequestBudget = "request budget exhausted"
+	StopTimeLimit     = "time limit reached"
+)
+
+// Create a new scanner for the given settings.  The settings should not be
+// modified once the scan has started.
+func NewScanner(settings *ss.ScanSettings) (*Scanner, error) {
+	var cp *checkpoint.Checkpoint
+	if settings.ResumePath != "" {
+		var err error
+		if cp, err = checkpoint.LoadCheckpoint(settings.ResumePath); err != nil {
+			return nil, err
+		}
+		if len(settings.BaseURLs) == 0 {
+			settings.BaseURLs = cp.BaseURLs()
+		}
+		if settings.CheckpointPath != "" {
+			cp.SetPath(settings.CheckpointPath)
+		}
+	} else if settings.CheckpointPath != "" {
+		cp = checkpoint.NewCheckpoint(settings.CheckpointPath, settings.BaseURLs)
+	}
+	if len(settings.BaseURLs) == 0 {
+		return nil, fmt.Errorf("No base URLs given.")
+	}
+	bases, err := settings.GetScopes()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := buildScope(settings, bases)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{
+		settings:   settings,
+		scope:      bases,
+		rules:      rules,
+		checkpoint: cp,
+		stats:      stats.New(),
+	}, nil
+}
+
+// Build the scope from the base URLs and the scope rules in the settings.
+func buildScope(settings *ss.ScanSettings, bases []*url.URL) (*scope.Scope, error) {
+	rules := scope.New()
+	for _, u := range bases {
+		rules.IncludeURL(u, settings.AllowHTTPSUpgrade)
+	}
+	if settings.ScopeFile != "" {
+		if err := rules.LoadFile(settings.ScopeFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := rules.AddRules(true, settings.ScopeRules...); err != nil {
+		return nil, err
+	}
+	if err := rules.AddRules(false, settings.ScopeExcludes...); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Call f for every result of the scan, including errors and missing pages.
+// It is called from a single goroutine, in the order results are produced.
+func (s *Scanner) OnResult(f func(*results.Result)) {
+	s.resultHook = f
+}
+
+// Call f whenever the amount of work done or to be done changes.
+func (s *Scanner) OnProgress(f func(done, total int64)) {
+	s.progressHook = f
+}
+
+// Get the live statistics of the scan.
+func (s *Scanner) Stats() *stats.Stats {
+	return s.stats
+}
+
+// Call f for every URL that could not be requested.
+func (s *Scanner) OnError(f func(u *url.URL, err error)) {
+	s.errorHook = f
+}
+
+// Use a ResultsManager to write results, in addition to any result hook.  By
+// default, results are only delivered to the hooks.
+func (s *Scanner) SetResultsManager(rm results.ResultsManager) {
+	s.resultsManager = rm
+}
+
+// Use a custom ClientFactory instead of one built from the settings.
+func (s *Scanner) SetClientFactory(factory client.ClientFactory) {
+	s.clientFactory = factory
+}
+
+// Run the scan until all work is done or the context is cancelled.  When the
+// context is cancelled, outstanding work is abandoned, results already found
+// are delivered, and the context's error is returned.  A Scanner can only be
+// run once.
+func (s *Scanner) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("Scanner has already been run.")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	settings := s.settings
+
+	// Load wordlist
+	words, err := wordlist.LoadWordlist(settings.WordlistPath)
+	if err != nil {
+		return fmt.Errorf("Unable to load wordlist: %s", err.Error())
+	}
+
+	s.stats.Start()
+
+	// Stop early when the request budget or time limit is reached
+	scanCtx, stopScan := context.WithCancel(ctx)
+	defer stopScan()
+	if settings.MaxTime > 0 {
+		var cancelTimeout context.CancelFunc
+		scanCtx, cancelTimeout = context.WithTimeout(scanCtx, settings.MaxTime)
+		defer cancelTimeout()
+	}
+	var budgetExhausted int32
+	budget := client.NewRequestBudget(settings.MaxRequests)
+	budget.OnExhausted(func() {
+		atomic.StoreInt32(&budgetExhausted, 1)
+		stopScan()
+	})
+
+	// Shared by all clients, and released when the scan stops
+	limiter := throttle.NewRateLimiter(settings.RequestRate(), settings.HostInterval())
+	defer limiter.Stop()
+
+	// Build an HTTP Client Factory
+	clientFactory := s.clientFactory
+	if clientFactory != nil && settings.MaxRequests > 0 {
+		logging.Logf(logging.LogWarning, "Request budget is not enforced for a custom client factory.")
+	}
+	if clientFactory != nil && len(settings.AllowedRanges) > 0 {
+		logging.Logf(logging.LogWarning, "Address allowlist is not enforced for a custom client factory.")
+	}
+	if clientFactory != nil && (settings.RequestRate() > 0 || settings.HostInterval() > 0) {
+		logging.Logf(logging.LogWarning, "Rate limits are not enforced for a custom client factory.")
+	}
+	if clientFactory == nil {
+		logging.Logf(logging.LogDebug, "Creating Client Factory...")
+		proxyFactory, err := client.NewProxyClientFactory(settings.Proxies, settings.Timeout, settings.UserAgent)
+		if err != nil {
+			return fmt.Errorf("Unable to build client factory: %s", err.Error())
+		}
+		proxyFactory.SetUsernamePassword(settings.HTTPUsername, settings.HTTPPassword)
+		proxyFactory.SetRequestBudget(budget)
+		proxyFactory.SetRateLimiter(limiter)
+		proxyFactory.SetContext(scanCtx)
+		if len(settings.AllowedRanges) > 0 {
+			allowlist, err := client.ParseAllowlist(settings.AllowedRanges)
+			if err != nil {
+				return err
+			}
+			proxyFactory.SetAllowlist(allowlist)
+		}
+		clientFactory = proxyFactory
+	}
+
+	var soft404 *worker.Soft404Detector
+	if settings.Soft404 && settings.RunMode != ss.RunModeLinkCheck {
+		soft404 = worker.NewSoft404Detector()
+	}
+
+	logging.Logf(logging.LogDebug, "Creating expander and filter...")
+	depthLimit := filter.NewDepthLimit(settings.MaxDepth, settings.MaxScopeDepth, s.scope)
+	var expander filter.Expander
+	switch settings.RunMode {
+	case ss.RunModeEnumeration:
+		wlexpander := filter.NewWordlistExpander(words, settings.AddSlashes, settings.MangleCases)
+		wlexpander.ProcessWordlist()
+		wlexpander.SetDepthLimit(depthLimit)
+		if soft404 != nil {
+			// Calibrate each directory before its names are requested
+			calibrator := worker.NewSoft404Calibrator(scanCtx, settings, clientFactory, soft404, s.stats)
+			wlexpander.OnExpand(calibrator.Calibrate)
+		}
+		expander = wlexpander
+	case ss.RunModeDotProduct:
+		dpexpander := filter.NewDotProductExpander(words)
+		expander = dpexpander
+	case ss.RunModeLinkCheck:
+		// No expander needed
+	default:
+		return fmt.Errorf("Unknown run mode: %d", settings.RunMode)
+	}
+
+	// Setup the main workqueue
+	logging.Logf(logging.LogDebug, "Starting work queue...")
+	queue := workqueue.NewWorkQueue(settings.QueueSize, s.scope, settings.AllowHTTPSUpgrade)
+	queue.SetScope(s.rules)
+	queue.SetOrder(settings.QueueOrder)
+	queue.SetHostWeights(settings.HostWeights)
+	if err := queue.SetSpill(settings.QueueMemory, settings.QueueSpillDir, settings.Header.Header()); err != nil {
+		return err
+	}
+	queue.GetCounter().SetStatusCallback(func(done, total int64) {
+		s.stats.SetProgress(done, total)
+		if s.progressHook != nil {
+			s.progressHook(done, total)
+		}
+	})
+	var replay []*results.Result
+	if s.checkpoint != nil {
+		queue.SetTracker(s.checkpoint)
+		s.checkpoint.SetCounter(queue.GetCounter().Counts)
+		s.checkpoint.SetContext(scanCtx)
+		replay = s.checkpoint.Results()
+		// Work for the unfinished tasks is counted again as they are expanded
+		if done, _ := s.checkpoint.WorkCounts(); done > 0 {
+			queue.GetCounter().Restore(done)
+		}
+	}
+	queue.RunInBackground()
+
+	if expander != nil {
+		expander.SetAddCount(queue.GetAddCount())
+	}
+
+	headerExpander := filter.NewHeaderExpander(settings.OptionalHeader.Header())
+	headerExpander.SetAddCount(queue.GetAddCount())
+	extensionExpander := filter.NewExtensionExpander(settings.Extensions)
+	extensionExpander.SetAddCount(queue.GetAddCount())
+
+	workFilter := filter.NewWorkFilter(settings, queue.GetDoneFunc())
+	workFilter.SetScope(s.rules)
+	workFilter.SetDepthLimit(depthLimit)
+	if s.checkpoint != nil {
+		workFilter.MarkDone(s.checkpoint.Done()...)
+	}
+
+	// Check robots mode
+	if settings.RobotsMode == ss.ObeyRobots {
+		workFilter.AddRobotsFilter(s.scope, clientFactory)
+		for host, delay := range workFilter.CrawlDelays() {
+			limiter.SetInterval(host, delay)
+		}
+	}
+
+	// filter paths after expansion
+	logging.Debugf("Starting expansion and filtering...")
+	workChan := queue.GetWorkChan()
+	if expander != nil {
+		workChan = expander.Expand(workChan)
+		workChan = headerExpander.Expand(workChan)
+		workChan = extensionExpander.Expand(workChan)
+	}
+	workChan = workFilter.RunFilter(workChan)
+
+	// Results from the workers are passed to the hooks, then on to the
+	// results manager.
+	rchan := make(chan *results.Result, settings.QueueSize)
+	var rmchan chan *results.Result
+	if s.resultsManager != nil {
+		logging.Logf(logging.LogDebug, "Starting results manager...")
+		rmchan = make(chan *results.Result, settings.QueueSize)
+		s.resultsManager.Run(rmchan)
+	}
+	fanoutDone := make(chan bool)
+	go s.dispatchResults(replay, rchan, rmchan, fanoutDone)
+
+	// Periodically save the state of the scan
+	var checkpointStop chan bool
+	var checkpointDone <-chan bool
+	if s.checkpoint != nil {
+		checkpointStop = make(chan bool)
+		checkpointDone = s.checkpoint.RunInBackground(settings.CheckpointInterval, checkpointStop)
+	}
+
+	logging.Logf(logging.LogDebug, "Starting %d workers...", settings.Workers)
+	workers := worker.StartWorkers(scanCtx, settings, clientFactory, workChan, queue.GetAddFunc(), queue.GetDoneFunc(), rchan, soft404, s.stats)
+
+	// Stop dispatching work if the scan is cancelled or a limit is reached
+	waitDone := make(chan bool)
+	watchDone := make(chan bool)
+	go func() {
+		defer close(watchDone)
+		select {
+		case <-scanCtx.Done():
+			reason := StopTimeLimit
+			if ctx.Err() != nil {
+				reason = StopCancelled
+			} else if atomic.LoadInt32(&budgetExhausted) != 0 {
+				reason = StopRequestBudget
+			}
+			logging.Logf(logging.LogInfo, "Stopping scan: %s", reason)
+			s.mu.Lock()
+			s.summary.StopReason = reason
+			s.mu.Unlock()
+			queue.Stop()
+			limiter.Stop()
+		case <-waitDone:
+		}
+	}()
+
+	// Kick things off with the seed URL
+	logging.Logf(logging.LogDebug, "Adding starting URLs: %v", s.scope)
+	task.SetDefaultHeader(settings.Header.Header())
+	tasks := make([]*task.Task, 0, len(s.scope))
+	for _, u := range s.scope {
+		tasks = append(tasks, task.NewTaskFromURL(u))
+	}
+	if s.checkpoint != nil {
+		// Previously queued tasks are expanded again, but the filter drops the
+		// ones already done.  Secret headers were not saved, so they come from
+		// the current settings.
+		queued := s.checkpoint.Queued()
+		for _, t := range queued {
+			t.Header = ss.RestoreHeader(t.Header, settings.Header.Header())
+		}
+		if len(queued) > 0 {
+			logging.Logf(logging.LogInfo, "Resuming %d queued tasks, %d results.", len(queued), len(replay))
+		}
+		tasks = append(tasks, queued...)
+	}
+	queue.AddTasks(tasks...)
+
+	// Potentially seed from robots
+	if settings.RobotsMode == ss.SeedRobots {
+		queue.SeedFromRobots(s.scope, clientFactory)
+	}
+	if settings.SeedSitemaps {
+		queue.SeedFromSitemaps(s.scope, clientFactory)
+	}
+
+	// Wait for work to be done
+	logging.Logf(logging.LogDebug, "Scanner waiting for work...")
+	queue.WaitPipe()
+	close(waitDone)
+	<-watchDone
+	logging.Logf(logging.LogDebug, "Work done.")
+
+	// Cleanup
+	queue.InputFinished()
+	close(rchan)
+	<-fanoutDone
+	if checkpointStop != nil {
+		close(checkpointStop)
+		<-checkpointDone
+	}
+	if s.resultsManager != nil {
+		logging.Debugf("Waiting for results manager.")
+		s.resultsManager.Wait()
+	}
+
+	s.mu.Lock()
+	s.summary.Requests = budget.Used()
+	s.summary.Stats = s.stats.Snapshot()
+	s.summary.URLsSeen, s.summary.DedupeMemory = workFilter.DedupeUsage()
+	s.summary.Unexplored = queue.Dropped()
+	for _, w := range workers {
+		s.summary.Unexplored += w.Skipped()
+	}
+	s.mu.Unlock()
+	return ctx.Err()
+}
+
+// Get the summary of the scan.  Only complete once Run has returned.
+func (s *Scanner) Summary() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.summary
+}
+
+// Pass each result to the hooks and the results manager, starting with any
+// results replayed from a checkpoint.
+func (s *Scanner) dispatchResults(replay []*results.Result, rchan <-chan *results.Result, rmchan chan<- *results.Result, done chan<- bool) {
+	defer close(done)
+	if rmchan != nil {
+		defer close(rmchan)
+	}
+	for _, r := range replay {
+		if s.resultHook != nil {
+			s.resultHook(r)
+		}
+		if rmchan != nil {
+			rmchan <- r
+		}
+	}
+	for r := range rchan {
+		if s.checkpoint != nil {
+			s.checkpoint.AddResult(r)
+		}
+		if r.Error != nil && s.errorHook != nil {
+			s.errorHook(r.URL, r.Error)
+		}
+		if s.resultHook != nil {
+			s.resultHook(r)
+		}
+		if rmchan != nil {
+			rmchan <- r
+		}
+	}
+}
@@ -0,0 +1,624 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"github.com/Matir/webborer/checkpoint"
+	"github.com/Matir/webborer/results"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/admin", "/admin/", "/login":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("ok"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func testSettings(t *testing.T, baseURL string) *ss.ScanSettings {
+	dir, err := ioutil.TempDir("", "scanner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	wl := filepath.Join(dir, "words.txt")
+	if err := ioutil.WriteFile(wl, []byte("admin\nlogin\nmissing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings := ss.NewCommandSettings("test", ss.FlagsAll)
+	settings.BaseURLs = []string{baseURL}
+	settings.WordlistPath = wl
+	settings.Workers = 2
+	settings.Extensions = nil
+	settings.Mangle = false
+	return settings
+}
+
+func TestNewScanner_NoURLs(t *testing.T) {
+	settings := ss.NewCommandSettings("test", ss.FlagsAll)
+	if _, err := NewScanner(settings); err == nil {
+		t.Error("Expected error with no base URLs.")
+	}
+}
+
+func TestScanner_Run(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	s, err := NewScanner(testSettings(t, srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	found := make(map[string]int)
+	s.OnResult(func(r *results.Result) {
+		found[r.URL.Path] = r.Code
+	})
+	var lastDone, lastTotal int64
+	s.OnProgress(func(done, total int64) {
+		lastDone, lastTotal = done, total
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	for _, p := range []string{"/", "/admin", "/login"} {
+		if found[p] != 200 {
+			t.Errorf("Expected %s to be found, got %d", p, found[p])
+		}
+	}
+	if found["/missing"] != 404 {
+		t.Errorf("Expected /missing to be 404, got %d", found["/missing"])
+	}
+	if lastTotal == 0 || lastDone != lastTotal {
+		t.Errorf("Expected progress to finish, got %d/%d", lastDone, lastTotal)
+	}
+	if err := s.Run(context.Background()); err == nil {
+		t.Error("Expected error running scanner twice.")
+	}
+}
+
+func TestScanner_RunCancelled(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	s, err := NewScanner(testSettings(t, srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	finished := make(chan error)
+	go func() {
+		finished <- s.Run(ctx)
+	}()
+	select {
+	case err := <-finished:
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Cancelled scan did not finish.")
+	}
+}
+
+func TestScanner_OnError(t *testing.T) {
+	// Nothing listens on a closed server
+	srv := testServer()
+	baseURL := srv.URL + "/"
+	srv.Close()
+
+	settings := testSettings(t, baseURL)
+	settings.Timeout = time.Second
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	errors := 0
+	s.OnError(func(_ *url.URL, _ error) {
+		errors++
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if errors == 0 {
+		t.Error("Expected errors to be reported.")
+	}
+}
+
+type testResultsManager struct {
+	results []*results.Result
+	done    chan bool
+	waited  bool
+}
+
+func (rm *testResultsManager) Run(rchan <-chan *results.Result) {
+	rm.done = make(chan bool)
+	go func() {
+		for r := range rchan {
+			rm.results = append(rm.results, r)
+		}
+		close(rm.done)
+	}()
+}
+
+func (rm *testResultsManager) Wait() {
+	<-rm.done
+	rm.waited = true
+}
+
+func TestScanner_CancelFlushesResults(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	s, err := NewScanner(testSettings(t, srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	rm := &testResultsManager{}
+	s.SetResultsManager(rm)
+	ctx, cancel := context.WithCancel(context.Background())
+	seen := 0
+	s.OnResult(func(_ *results.Result) {
+		seen++
+		cancel()
+	})
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if !rm.waited {
+		t.Error("Expected results manager to be finished.")
+	}
+	if len(rm.results) != seen || seen == 0 {
+		t.Errorf("Expected %d results written, got %d", seen, len(rm.results))
+	}
+}
+
+func TestScanner_Resume(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/", "/admin", "/login":
+			w.Write([]byte("ok"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.Workers = 1
+	settings.CheckpointPath = filepath.Join(filepath.Dir(settings.WordlistPath), "scan.checkpoint")
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.OnResult(func(r *results.Result) {
+		if r.URL.Path == "/" {
+			cancel()
+		}
+	})
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	firstRun := make(map[string]int)
+	for k, v := range requests {
+		firstRun[k] = v
+	}
+
+	resumed := testSettings(t, "")
+	resumed.BaseURLs = nil
+	resumed.ResumePath = settings.CheckpointPath
+	s, err = NewScanner(resumed)
+	if err != nil {
+		t.Fatalf("Error resuming scanner: %s", err)
+	}
+	found := make(map[string]int)
+	s.OnResult(func(r *results.Result) {
+		found[r.URL.Path] = r.Code
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running resumed scan: %s", err)
+	}
+	for p, c := range firstRun {
+		if requests[p] != c {
+			t.Errorf("Expected %s not to be requested again, requested %d times", p, requests[p]-c)
+		}
+	}
+	for _, p := range []string{"/", "/admin", "/login"} {
+		if found[p] != 200 {
+			t.Errorf("Expected %s to be found, got %d", p, found[p])
+		}
+	}
+	if found["/missing"] != 404 {
+		t.Errorf("Expected /missing to be 404, got %d", found["/missing"])
+	}
+}
+
+func TestScanner_ResumeSecretHeaders(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path] = r.Header.Get("Authorization")
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, "")
+	settings.BaseURLs = nil
+	settings.ResumePath = filepath.Join(filepath.Dir(settings.WordlistPath), "scan.checkpoint")
+	settings.Header.Set("Authorization: Bearer new")
+	cp := checkpoint.NewCheckpoint(settings.ResumePath, []string{srv.URL + "/"})
+	u, _ := url.Parse(srv.URL + "/admin/")
+	queued := task.NewTaskFromURL(u)
+	queued.Header = http.Header{"Authorization": {"Bearer old"}}
+	cp.Accepted(queued)
+	if err := cp.Save(); err != nil {
+		t.Fatalf("Error saving checkpoint: %s", err)
+	}
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error resuming scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running resumed scan: %s", err)
+	}
+	if _, ok := requests["/admin/login"]; !ok {
+		t.Fatal("Expected queued task to be expanded.")
+	}
+	for p, auth := range requests {
+		if auth != "Bearer new" {
+			t.Errorf("Expected %s to be requested with the current Authorization, got %q", p, auth)
+		}
+	}
+}
+
+func TestScanner_CheckpointFinished(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+	settings := testSettings(t, srv.URL+"/")
+	settings.CheckpointPath = filepath.Join(filepath.Dir(settings.WordlistPath), "scan.checkpoint")
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scanner: %s", err)
+	}
+	cp, err := checkpoint.LoadCheckpoint(settings.CheckpointPath)
+	if err != nil {
+		t.Fatalf("Error loading checkpoint: %s", err)
+	}
+	if queued := cp.Queued(); len(queued) != 0 {
+		t.Errorf("Expected no unfinished tasks, got %v", queued)
+	}
+	if done, todo := cp.WorkCounts(); done == 0 || done != todo {
+		t.Errorf("Expected all work done, got %d/%d", done, todo)
+	}
+}
+
+func TestScanner_MaxDepth(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.AddSlashes = true
+	settings.MaxDepth = 1
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	found := make(map[string]int)
+	s.OnResult(func(r *results.Result) {
+		found[r.URL.Path] = r.Code
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if found["/admin/"] != 200 {
+		t.Errorf("Expected /admin/ to be found, got %d", found["/admin/"])
+	}
+	if _, ok := found["/admin/login"]; ok {
+		t.Error("Expected /admin/login to be beyond the maximum depth.")
+	}
+}
+
+func TestScanner_ScopeExclude(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.AddSlashes = true
+	settings.ScopeExcludes = []string{"prefix=/admin/"}
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	found := make(map[string]int)
+	s.OnResult(func(r *results.Result) {
+		found[r.URL.Path] = r.Code
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if found["/login"] != 200 {
+		t.Errorf("Expected /login to be found, got %d", found["/login"])
+	}
+	if _, ok := found["/admin/"]; ok {
+		t.Error("Expected /admin/ to be out of scope.")
+	}
+}
+
+func TestScanner_AllowedRanges(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.AllowedRanges = []string{"192.0.2.0/24"}
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	errors := 0
+	s.OnError(func(_ *url.URL, _ error) {
+		errors++
+	})
+	s.OnResult(func(r *results.Result) {
+		if r.Error == nil {
+			t.Errorf("Expected no responses outside the allowed ranges, got %s", r.URL)
+		}
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if errors == 0 {
+		t.Error("Expected refused connections to be reported.")
+	}
+}
+
+func TestNewScanner_BadScope(t *testing.T) {
+	settings := testSettings(t, "http://localhost/")
+	settings.ScopeRules = []string{"path=["}
+	if _, err := NewScanner(settings); err == nil {
+		t.Error("Expected error for invalid scope rule.")
+	}
+}
+
+func TestScanner_MaxRequests(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.MaxRequests = 3
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if hits > 3 {
+		t.Errorf("Expected at most 3 requests, server got %d", hits)
+	}
+	summary := s.Summary()
+	if summary.StopReason != StopRequestBudget {
+		t.Errorf("Expected stop for request budget, got %q", summary.StopReason)
+	}
+	if summary.Requests != int64(hits) {
+		t.Errorf("Expected %d requests in summary, got %d", hits, summary.Requests)
+	}
+	if summary.Unexplored == 0 {
+		t.Error("Expected unexplored tasks.")
+	}
+}
+
+func TestScanner_MaxTime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.Workers = 1
+	settings.MaxTime = 50 * time.Millisecond
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	summary := s.Summary()
+	if summary.StopReason != StopTimeLimit {
+		t.Errorf("Expected stop for time limit, got %q", summary.StopReason)
+	}
+	if summary.Unexplored == 0 {
+		t.Error("Expected unexplored tasks.")
+	}
+}
+
+func TestScanner_SummaryComplete(t *testing.T) {
+	srv := testServer()
+	defer srv.Close()
+
+	s, err := NewScanner(testSettings(t, srv.URL+"/"))
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	summary := s.Summary()
+	if summary.StopReason != "" || summary.Unexplored != 0 || summary.Requests == 0 {
+		t.Errorf("Unexpected summary for a complete scan: %+v", summary)
+	}
+}
+
+func TestScanner_Sitemaps(t *testing.T) {
+	var mu sync.Mutex
+	sources := make(map[string]bool)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sources["other"+r.URL.Path] = true
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sources[r.URL.Path] = true
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nSitemap: /map.xml\nSitemap: %s/map.xml\n", other.URL)
+		case "/map.xml":
+			w.Write([]byte("<urlset><url><loc>/hidden/page</loc></url><url><loc>http://other.invalid/x</loc></url></urlset>"))
+		case "/", "/hidden/page":
+			w.Write([]byte("ok"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.SeedSitemaps = true
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	found := make(map[string]int)
+	s.OnResult(func(r *results.Result) {
+		found[r.URL.String()] = r.Code
+	})
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if found[srv.URL+"/hidden/page"] != 200 {
+		t.Errorf("Expected sitemap URL to be scanned, got %v", found)
+	}
+	if _, ok := found["http://other.invalid/x"]; ok {
+		t.Error("Expected out of scope sitemap URL to be skipped.")
+	}
+	if !sources["/sitemap.xml"] {
+		t.Error("Expected /sitemap.xml to be probed.")
+	}
+	if sources["other/map.xml"] {
+		t.Error("Expected out of scope sitemap not to be loaded.")
+	}
+}
+
+func TestScanner_CrawlDelay(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 0.05\n"))
+			return
+		}
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.Workers = 4
+	settings.RobotsMode = ss.ObeyRobots
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if len(starts) < 3 {
+		t.Fatalf("Expected at least 3 requests, got %d", len(starts))
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 40*time.Millisecond {
+			t.Errorf("Requests %d and %d only %s apart", i-1, i, gap)
+		}
+	}
+}
+
+func TestScanner_MaxHostConns(t *testing.T) {
+	var mu sync.Mutex
+	active, peak, hits := 0, 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		hits++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	settings := testSettings(t, srv.URL+"/")
+	settings.Workers = 4
+	settings.MaxHostConns = 1
+	s, err := NewScanner(settings)
+	if err != nil {
+		t.Fatalf("Error creating scanner: %s", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Error running scan: %s", err)
+	}
+	if hits < 4 || peak != 1 {
+		t.Errorf("Expected requests one at a time, got %d requests with peak %d", hits, peak)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scope decides which URLs a scan may request.
+//
+// A Scope is a list of include rules and a list of exclude rules.  A URL is in
+// scope if it matches any include rule and no exclude rule.  Each rule
+// matches on scheme, host, port and path; a rule can be written as a URL
+// pattern or as a list of fields:
+//
+//	*.example.com
+//	http,https://www.example.com:8080/app/
+//	host=*.example.com port=443,8443 path=^/api/v[0-9]+/
+//
+// A host of *.example.com matches any subdomain of example.com, but not
+// example.com itself.  A path in a URL pattern is a prefix, while path= is a
+// regular expression.
+package scope
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Matir/webborer/util"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule matches URLs.  Empty fields match anything.
+type Rule struct {
+	Schemes []string
+	// Host name, or *.domain for any subdomain of domain
+	Host string
+	// Ports, with the scheme's default port used for URLs without one
+	Ports []string
+	// Path prefix, matched on segment boundaries
+	PathPrefix string
+	// Regular expression for the path
+	PathRegexp *regexp.Regexp
+}
+
+// Scope is a set of include and exclude rules.
+type Scope struct {
+	include []*Rule
+	exclude []*Rule
+}
+
+// Create an empty scope, which contains nothing.
+func New() *Scope {
+	return &Scope{}
+}
+
+// Include a URL and everything below it.  With allowUpgrades, the https
+// version of an http URL is also included.
+func (s *Scope) IncludeURL(u *url.URL, allowUpgrades bool) {
+	s.Include(ruleForURL(u))
+	if allowUpgrades && u.Scheme == "http" {
+		upgraded := *u
+		upgraded.Scheme = "https"
+		s.Include(ruleForURL(&upgraded))
+	}
+}
+
+// Add an include rule.
+func (s *Scope) Include(r *Rule) {
+	s.include = append(s.include, r)
+}
+
+// Add an exclude rule.
+func (s *Scope) Exclude(r *Rule) {
+	s.exclude = append(s.exclude, r)
+}
+
+// Parse and add include or exclude rules.
+func (s *Scope) AddRules(include bool, rules ...string) error {
+	for _, text := range rules {
+		r, err := ParseRule(text)
+		if err != nil {
+			return err
+		}
+		if include {
+			s.Include(r)
+		} else {
+			s.Exclude(r)
+		}
+	}
+	return nil
+}
+
+// Load rules from a file.  Each line is "include RULE" or "exclude RULE";
+// blank lines and lines starting with # are ignored.
+func (s *Scope) LoadFile(path string) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Unable to open scope file: %s", err.Error())
+	}
+	defer fp.Close()
+	sc := bufio.NewScanner(fp)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		pieces := strings.SplitN(line, " ", 2)
+		if len(pieces) != 2 {
+			return fmt.Errorf("%s:%d: expected include or exclude and a rule", path, lineNo)
+		}
+		var include bool
+		switch strings.ToLower(pieces[0]) {
+		case "include":
+			include = true
+		case "exclude":
+			include = false
+		default:
+			return fmt.Errorf("%s:%d: expected include or exclude, got %s", path, lineNo, pieces[0])
+		}
+		if err := s.AddRules(include, strings.TrimSpace(pieces[1])); err != nil {
+			return fmt.Errorf("%s:%d: %s", path, lineNo, err.Error())
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("Unable to read scope file: %s", err.Error())
+	}
+	return nil
+}
+
+// Check if a URL is in scope.
+func (s *Scope) Contains(u *url.URL) bool {
+	if s == nil {
+		return false
+	}
+	for _, r := range s.exclude {
+		if r.Matches(u) {
+			return false
+		}
+	}
+	for _, r := range s.include {
+		if r.Matches(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a URL matches the rule.
+func (r *Rule) Matches(u *url.URL) bool {
+	if len(r.Schemes) > 0 && !util.StringSliceContains(r.Schemes, strings.ToLower(u.Scheme)) {
+		return false
+	}
+	if !matchHost(r.Host, strings.ToLower(u.Hostname())) {
+		return false
+	}
+	if len(r.Ports) > 0 && !util.StringSliceContains(r.Ports, portOf(u)) {
+		return false
+	}
+	if r.PathPrefix != "" && !util.URLIsSubpath(&url.URL{Path: r.PathPrefix}, u) {
+		return false
+	}
+	if r.PathRegexp != nil && !r.PathRegexp.MatchString(u.Path) {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) String() string {
+	fields := make([]string, 0)
+	if len(r.Schemes) > 0 {
+		fields = append(fields, "scheme="+strings.Join(r.Schemes, ","))
+	}
+	if r.Host != "" {
+		fields = append(fields, "host="+r.Host)
+	}
+	if len(r.Ports) > 0 {
+		fields = append(fields, "port="+strings.Join(r.Ports, ","))
+	}
+	if r.PathPrefix != "" {
+		fields = append(fields, "prefix="+r.PathPrefix)
+	}
+	if r.PathRegexp != nil {
+		fields = append(fields, "path="+r.PathRegexp.String())
+	}
+	if len(fields) == 0 {
+		return "*"
+	}
+	return strings.Join(fields, " ")
+}
+
+// Parse a rule written as a URL pattern or as space-separated key=value
+// fields.  Fields are scheme, host, port, prefix and path.
+func ParseRule(text string) (*Rule, error) {
+	r := &Rule{}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Empty scope rule.")
+	}
+	for _, field := range fields {
+		pieces := strings.SplitN(field, "=", 2)
+		if len(pieces) == 1 {
+			if err := r.parsePattern(field); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value := pieces[1]
+		switch strings.ToLower(pieces[0]) {
+		case "scheme":
+			r.Schemes = splitList(value)
+		case "host":
+			r.Host = strings.ToLower(value)
+		case "port":
+			r.Ports = splitList(value)
+		case "prefix":
+			r.PathPrefix = value
+		case "path":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid path regexp in scope rule %q: %s", text, err.Error())
+			}
+			r.PathRegexp = re
+		default:
+			return nil, fmt.Errorf("Unknown field %s in scope rule %q", pieces[0], text)
+		}
+	}
+	return r, nil
+}
+
+// Parse a [schemes://]host[:port][/prefix] pattern.
+func (r *Rule) parsePattern(text string) error {
+	pattern := text
+	if i := strings.Index(pattern, "://"); i != -1 {
+		if schemes := pattern[:i]; schemes != "*" {
+			r.Schemes = splitList(schemes)
+		}
+		pattern = pattern[i+3:]
+	}
+	if i := strings.IndexByte(pattern, '/'); i != -1 {
+		r.PathPrefix = pattern[i:]
+		pattern = pattern[:i]
+	}
+	if i := strings.LastIndexByte(pattern, ':'); i != -1 && !strings.HasSuffix(pattern, "]") {
+		r.Ports = splitList(pattern[i+1:])
+		pattern = pattern[:i]
+	}
+	if pattern == "" {
+		return fmt.Errorf("Missing host in scope rule %q", text)
+	}
+	if pattern != "*" {
+		r.Host = strings.ToLower(strings.Trim(pattern, "[]"))
+	}
+	return nil
+}
+
+// Build a rule for a URL and everything below it.
+func ruleForURL(u *url.URL) *Rule {
+	r := &Rule{
+		Host:       strings.ToLower(u.Hostname()),
+		PathPrefix: u.Path,
+	}
+	if u.Scheme != "" {
+		r.Schemes = []string{strings.ToLower(u.Scheme)}
+		r.Ports = []string{portOf(u)}
+	}
+	return r
+}
+
+func matchHost(pattern, host string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// Get the port of a URL, using the default for its scheme if there is none.
+func portOf(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+func splitList(s string) []string {
+	res := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scope
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustParse(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"*.example.com", "host=*.example.com"},
+		{"http,https://www.example.com:8080/app/", "scheme=http,https host=www.example.com port=8080 prefix=/app/"},
+		{"*://*:443", "port=443"},
+		{"[::1]:8080", "host=::1 port=8080"},
+		{"host=*.Example.com port=443,8443 path=^/api/v[0-9]{1,2}/", "host=*.example.com port=443,8443 path=^/api/v[0-9]{1,2}/"},
+		{"scheme=HTTPS prefix=/admin", "scheme=https prefix=/admin"},
+	}
+	for _, c := range cases {
+		r, err := ParseRule(c.text)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", c.text, err)
+			continue
+		}
+		if r.String() != c.want {
+			t.Errorf("Parsing %q: expected %q, got %q", c.text, c.want, r.String())
+		}
+	}
+	for _, bad := range []string{"", "http://", "path=[", "color=red"} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Errorf("Expected error parsing %q", bad)
+		}
+	}
+}
+
+func TestScope_Contains(t *testing.T) {
+	s := New()
+	s.IncludeURL(mustParse("http://www.example.com/app"), false)
+	if err := s.AddRules(true, "*.example.com", "scheme=https host=example.com port=8443"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddRules(false, "sso.example.com", "path=\\.pdf$"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		u  string
+		in bool
+	}{
+		{"http://www.example.com/app/login", true},
+		{"http://www.example.com:80/app", true},
+		{"https://www.example.com/other", true},
+		{"http://api.dev.example.com/", true},
+		{"http://sso.example.com/login", false},
+		{"http://www.example.com/app/doc.pdf", false},
+		{"http://example.com/", false},
+		{"https://example.com:8443/", true},
+		{"https://example.com/", false},
+		{"http://notexample.com/", false},
+	}
+	for _, c := range cases {
+		if got := s.Contains(mustParse(c.u)); got != c.in {
+			t.Errorf("Contains(%s): expected %v, got %v", c.u, c.in, got)
+		}
+	}
+	var empty *Scope
+	if empty.Contains(mustParse("http://www.example.com/")) {
+		t.Error("Expected nil scope to contain nothing.")
+	}
+}
+
+func TestScope_IncludeURLUpgrade(t *testing.T) {
+	s := New()
+	s.IncludeURL(mustParse("http://localhost:8080/foo"), true)
+	if !s.Contains(mustParse("https://localhost:8080/foo/bar")) {
+		t.Error("Expected upgraded URL in scope.")
+	}
+	if s.Contains(mustParse("https://localhost/foo/bar")) {
+		t.Error("Expected other port out of scope.")
+	}
+}
+
+func TestScope_LoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scope.txt")
+	data := "# Engagement scope\ninclude *.example.com\n\nexclude sso.example.com\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New()
+	if err := s.LoadFile(path); err != nil {
+		t.Fatalf("Error loading scope file: %s", err)
+	}
+	if !s.Contains(mustParse("https://www.example.com/")) || s.Contains(mustParse("https://sso.example.com/")) {
+		t.Error("Scope file rules not applied.")
+	}
+	if err := ioutil.WriteFile(path, []byte("allow *.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().LoadFile(path); err == nil {
+		t.Error("Expected error for unknown rule type.")
+	}
+	if err := New().LoadFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing file.")
+	}
+}
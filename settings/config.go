@@ -0,0 +1,287 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Flags that have no meaning inside of a config file.
+var cmdlineOnlyFlags = map[string]bool{
+	"config":      true,
+	"profile":     true,
+	"dump-config": true,
+	"resume":      true,
+}
+
+// Prefix for section names that define a profile.
+const profileSectionPrefix = "profile."
+
+// Setting within a profile that names the profile it inherits from.
+const profileInheritKey = "inherits"
+
+// Flags which store their values in the same place as another flag.
+var flagAliases = map[string]string{
+	"url_file": "url",
+}
+
+// Flag values that can discard their current (default) contents.
+type resettable interface {
+	Reset()
+}
+
+// Wraps a multi-valued flag on the command line so that its first value
+// replaces the default, as in config files and the environment.  Later values
+// are added.  Flags sharing a destination share reset.
+type replaceDefault struct {
+	flag.Value
+	dest  resettable
+	reset *bool
+}
+
+func (f *replaceDefault) String() string {
+	if f.Value == nil {
+		return ""
+	}
+	return f.Value.String()
+}
+
+func (f *replaceDefault) Set(value string) error {
+	if !*f.reset {
+		*f.reset = true
+		f.dest.Reset()
+	}
+	return f.Value.Set(value)
+}
+
+// Wrap the multi-valued flags in fs with replaceDefault while it is parsed.
+// The returned function unwraps them.
+func replaceDefaults(fs *flag.FlagSet) func() {
+	wrapped := make(map[*flag.Flag]flag.Value)
+	reset := make(map[string]*bool)
+	fs.VisitAll(func(f *flag.Flag) {
+		destFlag := fs.Lookup(flagDest(f.Name))
+		if destFlag == nil {
+			return
+		}
+		dest, ok := destFlag.Value.(resettable)
+		if !ok {
+			return
+		}
+		if reset[destFlag.Name] == nil {
+			reset[destFlag.Name] = new(bool)
+		}
+		wrapped[f] = f.Value
+		f.Value = &replaceDefault{Value: f.Value, dest: dest, reset: reset[destFlag.Name]}
+	})
+	return func() {
+		for f, v := range wrapped {
+			f.Value = v
+		}
+	}
+}
+
+type configEntry struct {
+	name  string
+	value string
+	line  int
+}
+
+// A named set of settings within a config file.
+type configProfile struct {
+	name     string
+	inherits string
+	entries  []configEntry
+	line     int
+}
+
+type configFile struct {
+	path    string
+	entries []configEntry
+	// Profiles by name
+	profiles map[string]*configProfile
+}
+
+// Config files are line-oriented.  Blank lines and lines beginning with '#' or
+// ';' are ignored.  Every other line has the form:
+//
+//	name = value
+//
+// where name is the name of a command-line flag (without the leading dash) and
+// value is parsed exactly as it would be on the command line.  Flags that
+// accept multiple values (url, header, extensions, spider-codes, ...) may be
+// repeated.  The first occurrence of such a flag in a file replaces its
+// default rather than appending to it, as on the command line.
+//
+// Named profiles are introduced by a section header and hold settings that
+// apply on top of those at the top of the file when the profile is selected
+// with -profile.  A profile may inherit from another profile:
+//
+//	[profile.stealth]
+//	workers = 2
+//	sleep = 2s
+//
+//	[profile.stealth-obey]
+//	inherits = stealth
+//	robots-mode = obey
+//
+// The path is used only for error messages.
+func parseConfig(r io.Reader, path string) (*configFile, error) {
+	cf := &configFile{path: path, profiles: make(map[string]*configProfile)}
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	var profile *configProfile
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("%s:%d: unterminated section header", path, lineNo)
+			}
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			if !strings.HasPrefix(section, profileSectionPrefix) {
+				return nil, fmt.Errorf("%s:%d: unknown section %s", path, lineNo, section)
+			}
+			name := strings.TrimPrefix(section, profileSectionPrefix)
+			if name == "" {
+				return nil, fmt.Errorf("%s:%d: missing profile name", path, lineNo)
+			}
+			if _, ok := cf.profiles[name]; ok {
+				return nil, fmt.Errorf("%s:%d: duplicate profile %s", path, lineNo, name)
+			}
+			profile = &configProfile{name: name, line: lineNo}
+			cf.profiles[name] = profile
+			continue
+		}
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"name = value\"", path, lineNo)
+		}
+		name := strings.TrimLeft(strings.TrimSpace(pieces[0]), "-")
+		if name == "" {
+			return nil, fmt.Errorf("%s:%d: missing setting name", path, lineNo)
+		}
+		entry := configEntry{
+			name:  name,
+			value: strings.TrimSpace(pieces[1]),
+			line:  lineNo,
+		}
+		switch {
+		case profile == nil:
+			cf.entries = append(cf.entries, entry)
+		case name == profileInheritKey:
+			profile.inherits = entry.value
+		default:
+			profile.entries = append(profile.entries, entry)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// A layer of config entries, from either the top of the file or a profile.
+type configLayer struct {
+	// Name of the profile, empty for the top of the file
+	profile string
+	entries []configEntry
+}
+
+// Get the layers of settings for the named profile, from the most general to
+// the most specific.  The top-level entries are always the first layer.
+func (cf *configFile) layers(profileName string) ([]configLayer, error) {
+	var profiles []configLayer
+	seen := make(map[string]bool)
+	for name := profileName; name != ""; {
+		if seen[name] {
+			return nil, fmt.Errorf("%s: profile %s inherits from itself", cf.path, name)
+		}
+		seen[name] = true
+		profile, ok := cf.profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: no such profile %s", cf.path, name)
+		}
+		profiles = append([]configLayer{{profile: name, entries: profile.entries}}, profiles...)
+		name = profile.inherits
+	}
+	return append([]configLayer{{entries: cf.entries}}, profiles...), nil
+}
+
+// Apply the entries in a config file to the settings, including those of the
+// selected profile.  Entries for flags that were given on the command line or
+// in the environment are skipped.
+func (settings *ScanSettings) applyConfig(cf *configFile) error {
+	layers, err := cf.layers(settings.profile)
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		if err := settings.applyConfigLayer(cf.path, layer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply a single layer of config entries.  The first entry for a multi-valued
+// flag replaces any value set by an earlier layer.
+func (settings *ScanSettings) applyConfigLayer(path string, layer configLayer) error {
+	fs := settings.configFlagSet()
+	reset := make(map[string]bool)
+	for _, e := range layer.entries {
+		f := fs.Lookup(e.name)
+		if f == nil {
+			return fmt.Errorf("%s:%d: unknown setting %s", path, e.line, e.name)
+		}
+		if cmdlineOnlyFlags[e.name] {
+			return fmt.Errorf("%s:%d: %s may only be given on the command line", path, e.line, e.name)
+		}
+		dest := flagDest(e.name)
+		if settings.Source(dest) > SourceFile {
+			continue
+		}
+		if !reset[dest] {
+			reset[dest] = true
+			if r, ok := fs.Lookup(dest).Value.(resettable); ok {
+				r.Reset()
+			}
+		}
+		if err := f.Value.Set(e.value); err != nil {
+			return fmt.Errorf("%s:%d: invalid value for %s: %s", path, e.line, e.name, err)
+		}
+		detail := fmt.Sprintf("%s:%d", path, e.line)
+		if layer.profile != "" {
+			detail += ", profile " + layer.profile
+		}
+		settings.setOrigin(dest, SourceFile, detail)
+	}
+	return nil
+}
+
+// Find the name of the flag that holds the value for the named flag.
+func flagDest(name string) string {
+	if dest, ok := flagAliases[name]; ok {
+		return dest
+	}
+	return name
+}
@@ -0,0 +1,315 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSettings() *ScanSettings {
+	return newScanSettings(flag.NewFlagSet("test", flag.ContinueOnError), FlagsAll)
+}
+
+func writeTestConfig(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "webborer")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	path := filepath.Join(dir, "webborer.conf")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("Unable to write config: %v", err)
+	}
+	return path
+}
+
+const testConfig = `
+# Engagement defaults
+workers = 7
+sleep = 250ms
+robots-mode = obey
+html = false
+extensions = bak
+extensions = old,inc
+spider-codes = 200, 403
+header = X-Engagement: 1234
+header = X-Team: red
+url = http://localhost/
+`
+
+func TestParseConfig(t *testing.T) {
+	cf, err := parseConfig(strings.NewReader(testConfig), "test.conf")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing config: %v", err)
+	}
+	if len(cf.entries) != 10 {
+		t.Fatalf("Expected 10 entries, got %d", len(cf.entries))
+	}
+	if cf.entries[0].name != "workers" || cf.entries[0].value != "7" {
+		t.Errorf("Unexpected first entry: %+v", cf.entries[0])
+	}
+	if cf.entries[0].line != 3 {
+		t.Errorf("Expected line 3, got %d", cf.entries[0].line)
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	if _, err := parseConfig(strings.NewReader("workers 7\n"), "test.conf"); err == nil {
+		t.Error("Expected error for line without '='.")
+	}
+	if _, err := parseConfig(strings.NewReader(" = 7\n"), "test.conf"); err == nil {
+		t.Error("Expected error for line without name.")
+	}
+}
+
+func TestLoadFromConfigFile(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := testSettings()
+	if err := ss.LoadFromConfigFile(path); err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+	if ss.Workers != 7 {
+		t.Errorf("Expected 7 workers, got %d", ss.Workers)
+	}
+	if ss.SleepTime != 250*time.Millisecond {
+		t.Errorf("Expected 250ms sleep, got %v", ss.SleepTime)
+	}
+	if ss.RobotsMode != ObeyRobots {
+		t.Errorf("Expected obey robots mode, got %v", ss.RobotsMode.String())
+	}
+	if ss.ParseHTML {
+		t.Error("Expected ParseHTML to be false.")
+	}
+	if ss.Extensions.String() != "bak,old,inc" {
+		t.Errorf("Expected extensions to replace defaults, got %s", ss.Extensions.String())
+	}
+	if ss.SpiderCodes.String() != "200,403" {
+		t.Errorf("Unexpected spider codes: %s", ss.SpiderCodes.String())
+	}
+	if ss.Header.Header().Get("X-Engagement") != "1234" || ss.Header.Header().Get("X-Team") != "red" {
+		t.Errorf("Unexpected headers: %v", ss.Header)
+	}
+	if len(ss.BaseURLs) != 1 {
+		t.Errorf("Expected 1 URL, got %v", ss.BaseURLs)
+	}
+	if ss.configPath != path {
+		t.Errorf("Expected configPath %s, got %s", path, ss.configPath)
+	}
+}
+
+func TestLoadFromConfigFile_FlagsOverride(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := testSettings()
+	args := []string{"-config", path, "-workers", "3", "-extensions", "cgi", "http://example.com/"}
+	if err := ss.ParseArgs(args); err != nil {
+		t.Fatalf("Unexpected error parsing args: %v", err)
+	}
+	if err := ss.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+	if ss.Workers != 3 {
+		t.Errorf("Expected flag to override workers, got %d", ss.Workers)
+	}
+	if ss.Extensions.String() != "cgi" {
+		t.Errorf("Expected flag to override extensions, got %s", ss.Extensions.String())
+	}
+	if ss.BaseURLs.String() != "http://example.com/" {
+		t.Errorf("Expected positional URL to override config, got %s", ss.BaseURLs.String())
+	}
+	if ss.SleepTime != 250*time.Millisecond {
+		t.Errorf("Expected sleep from config, got %v", ss.SleepTime)
+	}
+}
+
+func TestMultiValuedFlags_ReplaceDefaults(t *testing.T) {
+	path := writeTestConfig(t, "extensions = cgi\nextensions = pl\nspider-codes = 403\nspider-codes = 401\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	load := map[string]func(*ScanSettings) error{
+		"flag": func(ss *ScanSettings) error {
+			return ss.ParseArgs([]string{"-extensions", "cgi", "-extensions", "pl", "-spider-codes", "403", "-spider-codes", "401"})
+		},
+		"env": func(ss *ScanSettings) error {
+			return ss.applyEnv([]string{"WEBBORER_EXTENSIONS=cgi\npl", "WEBBORER_SPIDER_CODES=403\n401"})
+		},
+		"file": func(ss *ScanSettings) error {
+			return ss.LoadFromConfigFile(path)
+		},
+	}
+	for source, fn := range load {
+		ss := testSettings()
+		if err := fn(ss); err != nil {
+			t.Fatalf("%s: unexpected error: %v", source, err)
+		}
+		if ss.Extensions.String() != "cgi,pl" {
+			t.Errorf("%s: expected extensions to replace defaults, got %s", source, ss.Extensions.String())
+		}
+		if ss.SpiderCodes.String() != "403,401" {
+			t.Errorf("%s: expected spider codes to replace defaults, got %s", source, ss.SpiderCodes.String())
+		}
+	}
+}
+
+func TestLoadFromConfigFile_Errors(t *testing.T) {
+	for _, contents := range []string{
+		"not-a-flag = 1\n",
+		"workers = many\n",
+		"config = /etc/other.conf\n",
+	} {
+		path := writeTestConfig(t, contents)
+		if err := testSettings().LoadFromConfigFile(path); err == nil {
+			t.Errorf("Expected error loading config %q", contents)
+		}
+		os.RemoveAll(filepath.Dir(path))
+	}
+	if err := testSettings().LoadFromConfigFile("/nonexistent/webborer.conf"); err == nil {
+		t.Error("Expected error loading missing file.")
+	}
+}
+
+const testProfileConfig = `
+workers = 16
+extensions = php
+
+[profile.stealth]
+workers = 2
+sleep = 2s
+
+[profile.stealth-obey]
+inherits = stealth
+robots-mode = obey
+extensions = html
+
+[profile.ci-linkcheck]
+mode = linkcheck
+`
+
+func TestParseConfig_Profiles(t *testing.T) {
+	cf, err := parseConfig(strings.NewReader(testProfileConfig), "test.conf")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing config: %v", err)
+	}
+	if len(cf.entries) != 2 {
+		t.Errorf("Expected 2 top-level entries, got %d", len(cf.entries))
+	}
+	if len(cf.profiles) != 3 {
+		t.Fatalf("Expected 3 profiles, got %d", len(cf.profiles))
+	}
+	if p := cf.profiles["stealth-obey"]; p.inherits != "stealth" || len(p.entries) != 2 {
+		t.Errorf("Unexpected profile: %+v", p)
+	}
+	for _, contents := range []string{
+		"[profile.a]\n[profile.a]\n",
+		"[profile.]\n",
+		"[other]\n",
+		"[profile.a\n",
+	} {
+		if _, err := parseConfig(strings.NewReader(contents), "test.conf"); err == nil {
+			t.Errorf("Expected error parsing %q", contents)
+		}
+	}
+}
+
+func TestLoadFromConfigFile_Profile(t *testing.T) {
+	path := writeTestConfig(t, testProfileConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := testSettings()
+	if err := ss.ParseArgs([]string{"-config", path, "-profile", "stealth-obey"}); err != nil {
+		t.Fatalf("Unexpected error parsing args: %v", err)
+	}
+	if err := ss.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+	if ss.Workers != 2 {
+		t.Errorf("Expected 2 workers from base profile, got %d", ss.Workers)
+	}
+	if ss.SleepTime != 2*time.Second {
+		t.Errorf("Expected 2s sleep from base profile, got %v", ss.SleepTime)
+	}
+	if ss.RobotsMode != ObeyRobots {
+		t.Errorf("Expected obey robots mode, got %v", ss.RobotsMode.String())
+	}
+	if ss.Extensions.String() != "html" {
+		t.Errorf("Expected profile to replace extensions, got %s", ss.Extensions.String())
+	}
+	if ss.RunMode != RunModeEnumeration {
+		t.Errorf("Expected enumeration mode, got %s", ss.RunMode.String())
+	}
+}
+
+func TestLoadFromConfigFile_ProfileErrors(t *testing.T) {
+	for profile, contents := range map[string]string{
+		"missing": testProfileConfig,
+		"a":       "[profile.a]\ninherits = b\n[profile.b]\ninherits = a\n",
+	} {
+		path := writeTestConfig(t, contents)
+		ss := testSettings()
+		ss.profile = profile
+		if err := ss.LoadFromConfigFile(path); err == nil {
+			t.Errorf("Expected error loading profile %s", profile)
+		}
+		os.RemoveAll(filepath.Dir(path))
+	}
+}
+
+func TestLoadFromConfigFile_CommandFlags(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := newScanSettings(flag.NewFlagSet("robots", flag.ContinueOnError), FlagsConfig|FlagsClient)
+	if ss.FlagSet().Lookup("workers") != nil {
+		t.Error("Expected workers flag not to be registered for command.")
+	}
+	if err := ss.ParseArgs([]string{"-workers", "3"}); err == nil {
+		t.Error("Expected error for flag not accepted by command.")
+	}
+	if ss.Workers != 0 {
+		t.Errorf("Expected no default for flag not accepted by command, got %d workers", ss.Workers)
+	}
+	if err := ss.LoadFromConfigFile(path); err != nil {
+		t.Fatalf("Expected config with other commands' settings to load, got %v", err)
+	}
+	if ss.Workers != 7 {
+		t.Errorf("Expected 7 workers from config, got %d", ss.Workers)
+	}
+}
+
+func TestLoadFromConfigFile_ScanMode(t *testing.T) {
+	path := writeTestConfig(t, "mode = linkcheck\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	groups := FlagsAll &^ FlagsLegacy
+	ss := newScanSettings(flag.NewFlagSet("scan", flag.ContinueOnError), groups)
+	if err := ss.ParseArgs([]string{"-mode", "linkcheck"}); err == nil {
+		t.Error("Expected error for linkcheck mode on command line.")
+	}
+	if err := ss.ParseArgs([]string{"-mode", "dotproduct"}); err != nil || ss.RunMode != RunModeDotProduct {
+		t.Errorf("Expected dotproduct mode, got %v, %s", err, ss.RunMode.String())
+	}
+	ss = newScanSettings(flag.NewFlagSet("scan", flag.ContinueOnError), groups)
+	if err := ss.LoadFromConfigFile(path); err == nil {
+		t.Error("Expected error for linkcheck mode in config.")
+	}
+}
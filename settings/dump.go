@@ -0,0 +1,253 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Where the value of a setting came from.  Sources are ordered by precedence:
+// a setting from a later source overrides one from an earlier source.
+type SettingSource int
+
+const (
+	SourceDefault = SettingSource(iota)
+	SourceFile
+	SourceEnv
+	SourceFlag
+	settingSourceMax
+)
+
+var settingSourceStrings = [...]string{
+	"default",
+	"file",
+	"env",
+	"flag",
+}
+
+func (s SettingSource) String() string {
+	return settingSourceStrings[s]
+}
+
+// The origin of the value of a single setting.
+type settingOrigin struct {
+	source SettingSource
+	// Additional information, such as the file and line
+	detail string
+}
+
+func (o settingOrigin) String() string {
+	if o.detail == "" {
+		return o.source.String()
+	}
+	return fmt.Sprintf("%s (%s)", o.source.String(), o.detail)
+}
+
+// Flags whose values are secret, and are not shown in -dump-config or logs.
+var secretFlags = map[string]bool{
+	"http-password": true,
+}
+
+// Headers whose values are secret.
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+}
+
+// Shown in place of a secret.
+const redactedValue = "<redacted>"
+
+// Hide a secret value of a flag.  Returns the value to show, and whether it
+// was changed.
+func redact(f *flag.Flag, value string) (string, bool) {
+	if secretFlags[f.Name] && value != "" {
+		return redactedValue, true
+	}
+	if _, ok := f.Value.(*HeaderFlag); ok {
+		pieces := strings.SplitN(value, ":", 2)
+		if len(pieces) == 2 && secretHeaders[http.CanonicalHeaderKey(strings.TrimSpace(pieces[0]))] {
+			return pieces[0] + ": " + redactedValue, true
+		}
+	}
+	return value, false
+}
+
+// Check if a header has any secret headers.
+func hasSecretHeader(h http.Header) bool {
+	for k := range h {
+		if secretHeaders[http.CanonicalHeaderKey(k)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Copy a header with the values of secret headers hidden, so it can be
+// written to results or other files.  Returns h if it has no secrets.
+func RedactHeader(h http.Header) http.Header {
+	if !hasSecretHeader(h) {
+		return h
+	}
+	res := make(http.Header, len(h))
+	for k, v := range h {
+		if secretHeaders[http.CanonicalHeaderKey(k)] {
+			v = []string{redactedValue}
+		}
+		res[k] = v
+	}
+	return res
+}
+
+// Copy a header with its secret headers replaced by those in from, to undo
+// RedactHeader with the current settings.  Returns h if neither has secrets.
+func RestoreHeader(h, from http.Header) http.Header {
+	if !hasSecretHeader(h) && !hasSecretHeader(from) {
+		return h
+	}
+	res := make(http.Header, len(h))
+	for k, v := range h {
+		if !secretHeaders[http.CanonicalHeaderKey(k)] {
+			res[k] = v
+		}
+	}
+	for k, v := range from {
+		if secretHeaders[http.CanonicalHeaderKey(k)] {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// Get the value of a flag for printing, with any secrets hidden.
+func printableValue(f *flag.Flag) string {
+	mv, ok := f.Value.(multiValued)
+	if !ok {
+		v, _ := redact(f, f.Value.String())
+		return v
+	}
+	vals := mv.values()
+	hidden := false
+	for i, v := range vals {
+		var changed bool
+		vals[i], changed = redact(f, v)
+		hidden = hidden || changed
+	}
+	if !hidden {
+		return f.Value.String()
+	}
+	return strings.Join(vals, ", ")
+}
+
+// Flag values that hold multiple values, each of which can be passed to Set
+// individually.
+type multiValued interface {
+	values() []string
+}
+
+func (f *StringSliceFlag) values() []string {
+	return []string(*f)
+}
+
+func (f *StringListFlag) values() []string {
+	return []string(*f)
+}
+
+func (f *IntSliceFlag) values() []string {
+	res := make([]string, 0, len(*f))
+	for _, v := range *f {
+		res = append(res, fmt.Sprintf("%d", v))
+	}
+	return res
+}
+
+func (f *HeaderFlag) values() []string {
+	keys := make([]string, 0, len(*f))
+	for k := range *f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make([]string, 0)
+	for _, k := range keys {
+		for _, v := range (*f)[k] {
+			res = append(res, k+": "+v)
+		}
+	}
+	return res
+}
+
+func (f *HostWeightFlag) values() []string {
+	res := make([]string, 0, len(*f))
+	for k, v := range *f {
+		res = append(res, fmt.Sprintf("%s=%d", k, v))
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Record where a setting came from.
+func (settings *ScanSettings) setOrigin(name string, source SettingSource, detail string) {
+	if settings.origins == nil {
+		settings.origins = make(map[string]settingOrigin)
+	}
+	settings.origins[name] = settingOrigin{source: source, detail: detail}
+}
+
+// Get the source of the named setting.
+func (settings *ScanSettings) Source(name string) SettingSource {
+	return settings.origins[flagDest(name)].source
+}
+
+// Write the effective settings in config file format.  Each setting is
+// preceded by a comment recording where its value came from, so the output
+// documents the configuration and can be loaded again with -config.  Secrets
+// are written as comments with their values hidden.
+func (settings *ScanSettings) WriteConfig(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "# Effective webborer configuration\n")
+	if settings.configPath != "" {
+		fmt.Fprintf(buf, "# Config file: %s\n", settings.configPath)
+	}
+	if settings.profile != "" {
+		fmt.Fprintf(buf, "# Profile: %s\n", settings.profile)
+	}
+	settings.flagSet().VisitAll(func(f *flag.Flag) {
+		if cmdlineOnlyFlags[f.Name] || flagDest(f.Name) != f.Name {
+			return
+		}
+		fmt.Fprintf(buf, "\n# %s: %s\n", f.Name, settings.origins[f.Name])
+		vals := []string{f.Value.String()}
+		if mv, ok := f.Value.(multiValued); ok {
+			vals = mv.values()
+			if len(vals) == 0 {
+				fmt.Fprintf(buf, "# (no values)\n")
+			}
+		}
+		for _, v := range vals {
+			if shown, hidden := redact(f, v); hidden {
+				fmt.Fprintf(buf, "# %s = %s\n", f.Name, shown)
+				continue
+			}
+			fmt.Fprintf(buf, "%s = %s\n", f.Name, v)
+		}
+	})
+	return buf.Flush()
+}
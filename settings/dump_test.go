@@ -0,0 +1,136 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfig_Provenance(t *testing.T) {
+	path := writeTestConfig(t, testProfileConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := testSettings()
+	args := []string{"-config", path, "-profile", "stealth", "-timeout", "5s"}
+	if err := ss.ParseArgs(args); err != nil {
+		t.Fatalf("Unexpected error parsing args: %v", err)
+	}
+	if err := ss.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+	expected := map[string]SettingSource{
+		"timeout":    SourceFlag,
+		"workers":    SourceFile,
+		"extensions": SourceFile,
+		"method":     SourceDefault,
+	}
+	for name, src := range expected {
+		if got := ss.Source(name); got != src {
+			t.Errorf("Expected %s to come from %s, got %s", name, src, got)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ss.WriteConfig(buf); err != nil {
+		t.Fatalf("Unexpected error writing config: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# timeout: flag\ntimeout = 5s\n",
+		"# workers: file (" + path + ":6, profile stealth)\nworkers = 2\n",
+		"# method: default\nmethod = GET\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\nprofile =") || strings.Contains(out, "\nconfig =") {
+		t.Errorf("Command-line only flags should not be written:\n%s", out)
+	}
+}
+
+func TestWriteConfig_RoundTrip(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := testSettings()
+	if err := ss.ParseArgs([]string{"-header", "X-Flag: yes"}); err != nil {
+		t.Fatalf("Unexpected error parsing args: %v", err)
+	}
+	if err := ss.LoadFromConfigFile(path); err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := ss.WriteConfig(buf); err != nil {
+		t.Fatalf("Unexpected error writing config: %v", err)
+	}
+
+	dumpPath := writeTestConfig(t, buf.String())
+	defer os.RemoveAll(filepath.Dir(dumpPath))
+	reloaded := testSettings()
+	if err := reloaded.LoadFromConfigFile(dumpPath); err != nil {
+		t.Fatalf("Unable to reload dumped config: %v\n%s", err, buf.String())
+	}
+	ss.configPath = ""
+	reloaded.configPath = ""
+	if ss.String() != reloaded.String() {
+		t.Errorf("Settings differ after reload:\n%s\n%s", ss.String(), reloaded.String())
+	}
+}
+
+func TestWriteConfig_Secrets(t *testing.T) {
+	ss := testSettings()
+	args := []string{"-http-password", "hunter2", "-header", "Authorization: Bearer hunter2", "-header", "X-Team: red"}
+	if err := ss.ParseArgs(args); err != nil {
+		t.Fatalf("Unexpected error parsing args: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := ss.WriteConfig(buf); err != nil {
+		t.Fatalf("Unexpected error writing config: %v", err)
+	}
+	for name, out := range map[string]string{"config": buf.String(), "flags": ss.String()} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("Expected secrets to be hidden in %s:\n%s", name, out)
+		}
+		if !strings.Contains(out, "X-Team: red") {
+			t.Errorf("Expected other headers in %s:\n%s", name, out)
+		}
+	}
+	for _, want := range []string{"# http-password = <redacted>\n", "# header = Authorization: <redacted>\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestRestoreHeader(t *testing.T) {
+	saved := RedactHeader(http.Header{"Authorization": {"Bearer old"}, "X-Team": {"red"}})
+	if saved.Get("Authorization") != redactedValue {
+		t.Fatalf("Expected Authorization to be hidden, got %v", saved)
+	}
+	h := RestoreHeader(saved, http.Header{"Authorization": {"Bearer new"}, "User-Agent": {"test"}})
+	if h.Get("Authorization") != "Bearer new" || h.Get("X-Team") != "red" || h.Get("User-Agent") != "" {
+		t.Errorf("Unexpected restored header: %v", h)
+	}
+	h = RestoreHeader(saved, http.Header{})
+	if _, ok := h["Authorization"]; ok || h.Get("X-Team") != "red" {
+		t.Errorf("Expected secret header to be removed, got %v", h)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"os"
+	"strings"
+)
+
+// Prefix for environment variables holding settings.
+const envPrefix = "WEBBORER_"
+
+// Get the name of the environment variable for a flag.  For example, the
+// -http-password flag is read from WEBBORER_HTTP_PASSWORD.
+func EnvVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// Load settings from WEBBORER_* environment variables.  Settings from the
+// command line take precedence over those from the environment.
+func (settings *ScanSettings) LoadEnvironment() error {
+	return settings.applyEnv(os.Environ())
+}
+
+// Apply settings from a list of environment variables in "key=value" form.
+// Flags that take multiple values (such as headers) accept one value per line.
+// Flags that may only be given on the command line are ignored.
+func (settings *ScanSettings) applyEnv(environ []string) error {
+	settings.InitFlags()
+	fs := settings.configFlagSet()
+	byVar := make(map[string]string)
+	for _, kv := range environ {
+		pieces := strings.SplitN(kv, "=", 2)
+		if len(pieces) == 2 && strings.HasPrefix(pieces[0], envPrefix) {
+			byVar[pieces[0]] = pieces[1]
+		}
+	}
+	// VisitAll is in lexical order, so url is reset before url_file is loaded.
+	names := make([]string, 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	reset := make(map[string]bool)
+	for _, name := range names {
+		envVar := EnvVarName(name)
+		value, ok := byVar[envVar]
+		if !ok {
+			continue
+		}
+		delete(byVar, envVar)
+		if cmdlineOnlyFlags[name] {
+			logging.Logf(logging.LogWarning, "Ignoring %s, -%s may only be given on the command line.", envVar, name)
+			continue
+		}
+		dest := flagDest(name)
+		if settings.Source(dest) > SourceEnv {
+			continue
+		}
+		if !reset[dest] {
+			reset[dest] = true
+			if r, ok := fs.Lookup(dest).Value.(resettable); ok {
+				r.Reset()
+			}
+		}
+		f := fs.Lookup(name)
+		values := []string{value}
+		if _, ok := f.Value.(multiValued); ok {
+			values = strings.Split(strings.TrimSpace(value), "\n")
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+		}
+		for _, v := range values {
+			if err := f.Value.Set(v); err != nil {
+				return fmt.Errorf("Invalid value for %s: %s", envVar, err)
+			}
+		}
+		settings.setOrigin(dest, SourceEnv, envVar)
+	}
+	for envVar := range byVar {
+		logging.Logf(logging.LogWarning, "Unknown setting in environment: %s", envVar)
+	}
+	return nil
+}
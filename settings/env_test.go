@@ -0,0 +1,119 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvVarName(t *testing.T) {
+	for flagName, expected := range map[string]string{
+		"workers":       "WEBBORER_WORKERS",
+		"http-password": "WEBBORER_HTTP_PASSWORD",
+		"url_file":      "WEBBORER_URL_FILE",
+	} {
+		if got := EnvVarName(flagName); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, flagName, got)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	ss := testSettings()
+	environ := []string{
+		"HOME=/root",
+		"WEBBORER_HTTP_PASSWORD= s3cret ",
+		"WEBBORER_HEADER=Authorization: Bearer abc\nX-Team: red",
+		"WEBBORER_EXTENSIONS=php,bak",
+		"WEBBORER_NOT_A_SETTING=1",
+	}
+	if err := ss.applyEnv(environ); err != nil {
+		t.Fatalf("Unexpected error applying environment: %v", err)
+	}
+	if ss.HTTPPassword != " s3cret " {
+		t.Errorf("Unexpected password: %q", ss.HTTPPassword)
+	}
+	if ss.Header.Header().Get("Authorization") != "Bearer abc" || ss.Header.Header().Get("X-Team") != "red" {
+		t.Errorf("Unexpected headers: %v", ss.Header)
+	}
+	if ss.Extensions.String() != "php,bak" {
+		t.Errorf("Expected environment to replace extensions, got %s", ss.Extensions.String())
+	}
+	if ss.Source("http-password") != SourceEnv {
+		t.Errorf("Expected password from env, got %s", ss.Source("http-password"))
+	}
+	if err := testSettings().applyEnv([]string{"WEBBORER_WORKERS=many"}); err == nil {
+		t.Error("Expected error for invalid value.")
+	}
+}
+
+func TestApplyEnv_CmdlineOnly(t *testing.T) {
+	ss := testSettings()
+	environ := []string{
+		"WEBBORER_CONFIG=/nonexistent.conf",
+		"WEBBORER_PROFILE=stealth",
+		"WEBBORER_RESUME=/tmp/scan.ckpt",
+		"WEBBORER_DUMP_CONFIG=true",
+		"WEBBORER_WORKERS=5",
+	}
+	if err := ss.applyEnv(environ); err != nil {
+		t.Fatalf("Unexpected error applying environment: %v", err)
+	}
+	for name := range cmdlineOnlyFlags {
+		if ss.Source(name) == SourceEnv {
+			t.Errorf("Expected %s not to be set from the environment.", name)
+		}
+	}
+	if ss.configPath != "" || ss.profile != "" || ss.ResumePath != "" || ss.DumpConfig {
+		t.Errorf("Expected command line only settings to be ignored, got %+v", ss)
+	}
+	if ss.Workers != 5 {
+		t.Errorf("Expected other settings from env, got %d workers", ss.Workers)
+	}
+}
+
+func TestApplyEnv_Precedence(t *testing.T) {
+	path := writeTestConfig(t, testConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ss := testSettings()
+	if err := ss.ParseArgs([]string{"-config", path, "-workers", "3"}); err != nil {
+		t.Fatalf("Unexpected error parsing args: %v", err)
+	}
+	environ := []string{"WEBBORER_WORKERS=5", "WEBBORER_SLEEP=1s", "WEBBORER_METHOD=HEAD"}
+	if err := ss.applyEnv(environ); err != nil {
+		t.Fatalf("Unexpected error applying environment: %v", err)
+	}
+	if err := ss.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+	if ss.Workers != 3 {
+		t.Errorf("Expected flag to override env, got %d workers", ss.Workers)
+	}
+	if ss.SleepTime.String() != "1s" {
+		t.Errorf("Expected env to override file, got %v", ss.SleepTime)
+	}
+	if ss.Method != "HEAD" {
+		t.Errorf("Expected method from env, got %s", ss.Method)
+	}
+	if ss.RobotsMode != ObeyRobots {
+		t.Errorf("Expected robots mode from file, got %s", ss.RobotsMode.String())
+	}
+	if src := ss.origins["sleep"].String(); src != "env (WEBBORER_SLEEP)" {
+		t.Errorf("Unexpected origin for sleep: %s", src)
+	}
+}
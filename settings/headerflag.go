@@ -46,3 +46,8 @@ func (f *HeaderFlag) Set(value string) error {
 func (f *HeaderFlag) Header() http.Header {
 	return http.Header(*f)
 }
+
+// Reset discards all headers.
+func (f *HeaderFlag) Reset() {
+	*f = make(HeaderFlag)
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HostWeightFlag is a flag.Value that takes comma-separated or repeated
+// host=weight pairs and turns them into a map of host to weight.
+type HostWeightFlag map[string]int
+
+func (f *HostWeightFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values(), ",")
+}
+
+func (f *HostWeightFlag) Set(value string) error {
+	if *f == nil {
+		*f = make(HostWeightFlag)
+	}
+	for _, v := range strings.Split(value, ",") {
+		pieces := strings.SplitN(strings.TrimSpace(v), "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return fmt.Errorf("Host weight must be host=weight, got %s.", v)
+		}
+		weight, err := strconv.Atoi(pieces[1])
+		if err != nil || weight <= 0 {
+			return fmt.Errorf("Host weight must be a positive integer, got %s.", pieces[1])
+		}
+		(*f)[strings.ToLower(pieces[0])] = weight
+	}
+	return nil
+}
+
+// Reset discards all values, including defaults.
+func (f *HostWeightFlag) Reset() {
+	*f = nil
+}
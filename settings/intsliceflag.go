@@ -46,3 +46,8 @@ func (f *IntSliceFlag) Set(value string) error {
 	}
 	return nil
 }
+
+// Reset discards all values, including defaults.
+func (f *IntSliceFlag) Reset() {
+	*f = nil
+}
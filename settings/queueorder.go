@@ -0,0 +1,59 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package settings
+
+import (
+	"fmt"
+)
+
+// Order in which queued tasks are dispatched
+type QueueOrderOption int
+
+const (
+	// First in, first out
+	QueueOrderFIFO = iota
+	// Shallowest tasks first
+	QueueOrderDepth
+	// Tasks from words earlier in the wordlist first
+	QueueOrderRank
+	// Tasks by how they were discovered: seeds, robots.txt, sitemaps,
+	// redirects, links, then wordlist guesses
+	QueueOrderSource
+	queueOrderMax
+)
+
+var queueOrderStrings = [...]string{
+	"fifo",
+	"depth",
+	"rank",
+	"source",
+}
+
+func (f *QueueOrderOption) String() string {
+	if f == nil {
+		return queueOrderStrings[QueueOrderFIFO]
+	}
+	return queueOrderStrings[*f]
+}
+
+func (f *QueueOrderOption) Set(value string) error {
+	for i, val := range queueOrderStrings {
+		if val == value {
+			*f = QueueOrderOption(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown Queue Order: %s", value)
+}
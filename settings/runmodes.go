@@ -52,3 +52,16 @@ func (f *RunModeOption) Set(value string) error {
 	}
 	return fmt.Errorf("Unknown Run Mode: %s", value)
 }
+
+// Run mode flag for the scan command, which leaves link checking to the
+// linkcheck command.
+type ScanModeFlag struct {
+	*RunModeOption
+}
+
+func (f ScanModeFlag) Set(value string) error {
+	if value == runModeStrings[RunModeLinkCheck] {
+		return fmt.Errorf("Run mode %s is only available as \"webborer linkcheck\".", value)
+	}
+	return f.RunModeOption.Set(value)
+}
@@ -39,14 +39,33 @@ type ScanSettings struct {
 	Workers int
 	// Exclusions
 	ExcludePaths StringSliceFlag
+	// Additional rules for URLs in scope
+	ScopeRules StringListFlag
+	// Rules for URLs out of scope
+	ScopeExcludes StringListFlag
+	// File with scope rules
+	ScopeFile string
 	// Proxies
 	Proxies StringSliceFlag
+	// IP ranges that may be connected to, empty for any
+	AllowedRanges StringSliceFlag
 	// Operating mode
 	RunMode RunModeOption
 	// Parse HTML for links?
 	ParseHTML bool
-	// Time to sleep between requests, per thread
+	// Time to sleep between requests, per worker.  Converted to a rate by
+	// RequestRate.
 	SleepTime time.Duration
+	// Number of times to retry requests that fail with transient errors
+	Retries int
+	// Delay before the first retry, doubled for each retry after
+	RetryBackoff time.Duration
+	// Maximum number of retries in the scan, 0 for unlimited
+	RetryBudget int64
+	// Maximum requests per second in total, 0 for unlimited
+	Rate float64
+	// Maximum requests per second to a single host, 0 for unlimited
+	HostRate float64
 	// Log file path
 	LogfilePath string
 	// Level of logging
@@ -57,6 +76,8 @@ type ScanSettings struct {
 	Extensions StringSliceFlag
 	// Whether or not to mangle by adding extensions
 	Mangle bool
+	// Whether to calibrate directories and suppress soft 404s
+	Soft404 bool
 	// How long should internal queues be sized
 	QueueSize int
 	// Timeout for network requests
@@ -87,36 +108,152 @@ type ScanSettings struct {
 	OptionalHeader HeaderFlag
 	// Progress bar
 	ProgressBar bool
+	// Print statistics at the end of a scan
+	PrintStats bool
 	// Add slashes
 	AddSlashes bool
 	// MangleCases
 	MangleCases bool
 	// Whether or not to do CPU Profiling
 	DebugCPUProf bool
-	// Config file used when loading (for debugging only)
+	// Print the effective configuration instead of scanning
+	DumpConfig bool
+	// Maximum depth of tasks from the seed URL, 0 for unlimited
+	MaxDepth int
+	// Maximum path depth below the scope URL, 0 for unlimited
+	MaxScopeDepth int
+	// Seed the scan with the URLs listed in sitemaps
+	SeedSitemaps bool
+	// Order to dispatch queued tasks in
+	QueueOrder QueueOrderOption
+	// Maximum number of queued tasks to hold in memory, 0 for unlimited
+	QueueMemory int
+	// Directory for queued tasks beyond QueueMemory
+	QueueSpillDir string
+	// False positive rate for deduplicating with a Bloom filter, 0 for exact
+	DedupeFPRate float64
+	// Number of URLs the Bloom filter is sized for
+	DedupeCapacity int64
+	// Comma-separated rules for recognising duplicate URLs
+	Canonicalize string
+	// Query parameters to ignore when recognising duplicate URLs
+	DropParams StringSliceFlag
+	// Relative share of the queue each host is served, default 1
+	HostWeights HostWeightFlag
+	// Maximum concurrent requests to a single host, 0 for unlimited
+	MaxHostConns int
+	// Most bytes of each response body to read and measure
+	MaxBodySize int64
+	// Minimum time between requests to a single host
+	MinHostInterval time.Duration
+	// Maximum number of HTTP requests, 0 for unlimited
+	MaxRequests int64
+	// Maximum duration of the scan, 0 for unlimited
+	MaxTime time.Duration
+	// File to periodically save scan state to
+	CheckpointPath string
+	// How often to save scan state
+	CheckpointInterval time.Duration
+	// File to resume a scan from
+	ResumePath string
+	// Config file used when loading
 	configPath string
+	// Profile selected from the config file
+	profile string
+	// Flags for these settings
+	flags *flag.FlagSet
+	// Groups of flags accepted on the command line
+	flagGroups FlagGroup
+	// Flags for every setting, used for config files and the environment
+	allFlags *flag.FlagSet
+	// Where each setting that is not a default came from
+	origins map[string]settingOrigin
 	// Have flags been set up?
 	flagsSet bool
 }
 
+// Groups of related flags, so each command only accepts the flags it uses.
+type FlagGroup int
+
+const (
+	// Selecting config files and profiles
+	FlagsConfig = FlagGroup(1 << iota)
+	// Logging and debugging
+	FlagsLogging
+	// Making HTTP requests
+	FlagsClient
+	// Scope and pacing of a scan
+	FlagsScan
+	// Loading and modifying the wordlist
+	FlagsWordlist
+	// Enumeration of paths from the wordlist
+	FlagsEnumeration
+	// Writing results
+	FlagsOutput
+	// Options of the command line without a command that now have a command
+	// of their own, such as -mode=linkcheck
+	FlagsLegacy
+	// Every flag
+	FlagsAll = FlagsConfig | FlagsLogging | FlagsClient | FlagsScan | FlagsWordlist | FlagsEnumeration | FlagsOutput | FlagsLegacy
+)
+
 var DefaultUserAgent = "WebBorer 0.01"
 var outputFormats []string
 
+// Rules for recognising duplicate URLs, as applied by filter.Canonicalizer.
+var canonicalizeRuleStrings = [...]string{
+	"path",
+	"encoding",
+	"case",
+	"query",
+	"session",
+}
+
+var defaultCanonicalizeRules = []string{"path", "encoding", "query", "session"}
+
+// Check a canonicalization rule name.  An empty rule is allowed so that
+// canonicalization can be disabled.
+func validCanonicalizeRule(rule string) bool {
+	if rule == "" {
+		return true
+	}
+	for _, r := range canonicalizeRuleStrings {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
 // Constructs a ScanSettings struct with all of the defaults to be used.
 func NewScanSettings() *ScanSettings {
+	return newScanSettings(flag.CommandLine, FlagsAll)
+}
+
+// Constructs a ScanSettings struct for a command, which accepts only the flags
+// in the given groups on its command line.
+func NewCommandSettings(name string, groups FlagGroup) *ScanSettings {
+	return newScanSettings(flag.NewFlagSet(name, flag.ExitOnError), groups)
+}
+
+func newScanSettings(flags *flag.FlagSet, groups FlagGroup) *ScanSettings {
 	settings := &ScanSettings{
-		Threads:        runtime.NumCPU(),
-		Extensions:     []string{"html", "php", "asp", "aspx", "js", "txt"},
-		Method:         "GET",
-		Mangle:         true,
-		QueueSize:      1024,
-		Timeout:        30 * time.Second,
-		LogLevel:       "WARNING",
-		SpiderCodes:    IntSliceFlag{200},
-		ProgressBar:    true,
-		RunMode:        RunModeEnumeration,
-		Header:         make(HeaderFlag),
-		OptionalHeader: make(HeaderFlag),
+		Threads:            runtime.NumCPU(),
+		Extensions:         []string{"html", "php", "asp", "aspx", "js", "txt"},
+		Method:             "GET",
+		Mangle:             true,
+		QueueSize:          1024,
+		Timeout:            30 * time.Second,
+		RetryBackoff:       time.Second,
+		CheckpointInterval: time.Minute,
+		LogLevel:           "WARNING",
+		SpiderCodes:        IntSliceFlag{200},
+		ProgressBar:        true,
+		RunMode:            RunModeEnumeration,
+		Header:             make(HeaderFlag),
+		OptionalHeader:     make(HeaderFlag),
+		flags:              flags,
+		flagGroups:         groups,
 	}
 	settings.InitFlags()
 	return settings
@@ -127,8 +264,12 @@ func NewScanSettings() *ScanSettings {
 // settings.
 func GetScanSettings() (*ScanSettings, error) {
 	settings := NewScanSettings()
-	settings.LoadFromDefaultConfigFiles()
-	settings.ParseFlags()
+	if err := settings.Load(os.Args[1:]); err != nil {
+		return nil, err
+	}
+	if settings.DumpConfig {
+		return settings, nil
+	}
 	if err := settings.Validate(); err != nil {
 		return nil, err
 	}
@@ -140,91 +281,265 @@ func (settings *ScanSettings) InitFlags() {
 	if settings.flagsSet {
 		return
 	}
+	if settings.flagGroups == 0 {
+		settings.flagGroups = FlagsAll
+	}
+	settings.registerFlags(settings.flagSet(), settings.flagGroups)
+	// Config files and the environment may contain settings for any command.
+	if settings.flagGroups == FlagsAll {
+		settings.allFlags = settings.flagSet()
+	} else {
+		settings.allFlags = flag.NewFlagSet(settings.flagSet().Name(), flag.ContinueOnError)
+		settings.registerFlags(settings.allFlags, settings.flagGroups)
+		// Registering a flag sets its default, which only the command's own
+		// flags should get.
+		defaults := *settings
+		settings.registerFlags(settings.allFlags, FlagsAll&^settings.flagGroups)
+		*settings = defaults
+	}
+	settings.flagsSet = true
+}
 
-	flag.Var(&settings.BaseURLs, "url", "Starting `URL` & scopes.")
-	flag.Var(&StringSliceFileFlag{&settings.BaseURLs}, "url_file", "Starting `URL` & scopes, loaded from a file.")
-	runModeHelp := fmt.Sprintf("Run `mode`. Options: [%s]", strings.Join(runModeStrings[:], ", "))
-	flag.Var(&settings.RunMode, "mode", runModeHelp)
-	flag.IntVar(&settings.Threads, "threads", runtime.NumCPU(), "Number of worker `threads`.")
-	flag.IntVar(&settings.Workers, "workers", runtime.NumCPU()*2, "Number of `workers`.")
-	flag.Var(&settings.ExcludePaths, "exclude", "List of `paths` to exclude from search.")
-	flag.BoolVar(&settings.ParseHTML, "html", true, "Parse HTML documents for links to follow.")
-	flag.BoolVar(&settings.AllowHTTPSUpgrade, "allow-upgrade", false, "Allow HTTP->HTTPS upgrades.")
-	sleepTimeValue := DurationFlag{&settings.SleepTime}
-	flag.Var(sleepTimeValue, "sleep", "Time (as `duration`) to sleep between requests.")
-	flag.StringVar(&settings.LogfilePath, "logfile", "", "Logfile `filename` (defaults to stderr)")
-	flag.StringVar(&settings.WordlistPath, "wordlist", "", "Wordlist `filename` to use (default built-in)")
-	flag.Var(&settings.Extensions, "extensions", "List of `extensions` to mangle with.")
-	flag.BoolVar(&settings.Mangle, "mangle", true, "Mangle by adding extensions.")
-	flag.BoolVar(&settings.MangleCases, "cases", false, "Modify the wordlist with alternate cases.")
-	flag.BoolVar(&settings.AddSlashes, "slashes", false, "Add slashes to paths to check for servers that don't redirect.")
-	flag.Var(&settings.Header, "header", "Headers to send with each request.")
-	flag.Var(&settings.OptionalHeader, "optional-header", "Headers to try sending one at a time.")
-	flag.Var(&settings.Proxies, "proxy", "Proxy or `proxies` to use.")
-	timeoutValue := DurationFlag{&settings.Timeout}
-	flag.Var(timeoutValue, "timeout", "Network connection timeout (`duration`).")
-	if len(outputFormats) > 1 {
-		formatHelp := fmt.Sprintf("Output `format`.  Options: [%s]", strings.Join(outputFormats, ", "))
-		flag.StringVar(&settings.OutputFormat, "format", outputFormats[0], formatHelp)
-	}
-	flag.StringVar(&settings.OutputPath, "outfile", "", "Output `file`, defaults to stdout.")
-	loglevelHelp := fmt.Sprintf("Log `level`.  Options: [%s]", strings.Join(logging.LogLevelStrings[:], ", "))
-	flag.StringVar(&settings.LogLevel, "loglevel", settings.LogLevel, loglevelHelp)
-	flag.StringVar(&settings.UserAgent, "user-agent", DefaultUserAgent, "`User-Agent` for requests")
-	flag.BoolVar(&settings.IncludeRedirects, "include-redirects", false, "Include redirects in reports.")
-	flag.Var(&settings.SpiderCodes, "spider-codes", "HTTP Response Codes to Continue Spidering On.")
-	robotsModeHelp := fmt.Sprintf("Robots `mode`.  Options: [%s]", strings.Join(robotsModeStrings[:], ", "))
-	flag.Var(&settings.RobotsMode, "robots-mode", robotsModeHelp)
-	flag.StringVar(&settings.HTTPUsername, "http-username", "", "Username to be used for HTTP Auth")
-	flag.StringVar(&settings.HTTPPassword, "http-password", "", "Password to be used for HTTP Auth")
-	flag.BoolVar(&settings.ProgressBar, "progress", true, "Display a progress bar on stderr.")
-	flag.StringVar(&settings.Method, "method", "GET", "HTTP Method to use.")
-
-	// Debugging flags
-	flag.BoolVar(&settings.DebugCPUProf, "debug-cpuprof", false, "[DEBUG] CPU Profiling")
+// Register the flags in the given groups with a flag set.
+func (settings *ScanSettings) registerFlags(fs *flag.FlagSet, groups FlagGroup) {
+	if groups&FlagsConfig != 0 {
+		fs.StringVar(&settings.configPath, "config", "", "Config `file` to load instead of the default locations.")
+		fs.StringVar(&settings.profile, "profile", "", "Named `profile` from the config file to use.")
+		fs.BoolVar(&settings.DumpConfig, "dump-config", false, "Print the effective configuration and exit.")
+	}
+	if groups&FlagsLogging != 0 {
+		fs.StringVar(&settings.LogfilePath, "logfile", "", "Logfile `filename` (defaults to stderr)")
+		loglevelHelp := fmt.Sprintf("Log `level`.  Options: [%s]", strings.Join(logging.LogLevelStrings[:], ", "))
+		fs.StringVar(&settings.LogLevel, "loglevel", settings.LogLevel, loglevelHelp)
+		// Debugging flags
+		fs.BoolVar(&settings.DebugCPUProf, "debug-cpuprof", false, "[DEBUG] CPU Profiling")
+	}
+	if groups&FlagsClient != 0 {
+		fs.Var(&settings.Proxies, "proxy", "Proxy or `proxies` to use.")
+		fs.Var(&settings.AllowedRanges, "allow-cidr", "Only connect to addresses in these CIDR `ranges`.")
+		timeoutValue := DurationFlag{&settings.Timeout}
+		fs.Var(timeoutValue, "timeout", "Network connection timeout (`duration`).")
+		fs.StringVar(&settings.UserAgent, "user-agent", DefaultUserAgent, "`User-Agent` for requests")
+		fs.StringVar(&settings.HTTPUsername, "http-username", "", "Username to be used for HTTP Auth")
+		fs.StringVar(&settings.HTTPPassword, "http-password", "", "Password to be used for HTTP Auth")
+	}
+	if groups&FlagsScan != 0 {
+		fs.Var(&settings.BaseURLs, "url", "Starting `URL` & scopes.")
+		fs.Var(&StringSliceFileFlag{&settings.BaseURLs}, "url_file", "Starting `URL` & scopes, loaded from a file.")
+		fs.IntVar(&settings.Threads, "threads", runtime.NumCPU(), "Number of worker `threads`.")
+		fs.IntVar(&settings.Workers, "workers", runtime.NumCPU()*2, "Number of `workers`.")
+		fs.Var(&settings.ExcludePaths, "exclude", "List of `paths` to exclude from search.")
+		fs.Var(&settings.ScopeRules, "scope", "Also include URLs matching `rule` in scope, such as *.example.com.")
+		fs.Var(&settings.ScopeExcludes, "scope-exclude", "Exclude URLs matching `rule` from scope.")
+		fs.StringVar(&settings.ScopeFile, "scope-file", "", "Load include and exclude scope rules from `file`.")
+		fs.BoolVar(&settings.AllowHTTPSUpgrade, "allow-upgrade", false, "Allow HTTP->HTTPS upgrades.")
+		sleepTimeValue := DurationFlag{&settings.SleepTime}
+		fs.Var(sleepTimeValue, "sleep", "Time (as `duration`) each worker sleeps between requests, as a rate limit.")
+		fs.IntVar(&settings.Retries, "retries", 2, "Number of `times` to retry requests that fail with network errors.")
+		fs.Var(DurationFlag{&settings.RetryBackoff}, "retry-backoff", "Delay (as `duration`) before the first retry, doubled for each retry after.")
+		fs.Int64Var(&settings.RetryBudget, "retry-budget", 0, "Maximum `retries` in the whole scan (0 for unlimited).")
+		fs.Float64Var(&settings.Rate, "rate", 0, "Maximum `requests` per second in total (0 for unlimited).")
+		fs.Float64Var(&settings.HostRate, "host-rate", 0, "Maximum `requests` per second to a single host (0 for unlimited).")
+		fs.Var(&settings.Header, "header", "Headers to send with each request.")
+		robotsModeHelp := fmt.Sprintf("Robots `mode`.  Options: [%s]", strings.Join(robotsModeStrings[:], ", "))
+		fs.Var(&settings.RobotsMode, "robots-mode", robotsModeHelp)
+		fs.BoolVar(&settings.SeedSitemaps, "sitemaps", false, "Seed the scan with URLs from sitemaps listed in robots.txt or at /sitemap.xml.")
+		fs.BoolVar(&settings.ProgressBar, "progress", true, "Display a progress bar on stderr.")
+		fs.BoolVar(&settings.PrintStats, "stats", true, "Print scan statistics on stderr when the scan ends.")
+		fs.StringVar(&settings.Method, "method", "GET", "HTTP Method to use.")
+		fs.Var(&settings.SpiderCodes, "spider-codes", "HTTP Response Codes to Continue Spidering On.")
+		queueOrderHelp := fmt.Sprintf("Queue `order`.  Options: [%s]", strings.Join(queueOrderStrings[:], ", "))
+		fs.Var(&settings.QueueOrder, "queue-order", queueOrderHelp)
+		fs.IntVar(&settings.QueueMemory, "queue-memory", 0, "Maximum queued `tasks` to keep in memory before spilling to disk (0 for unlimited).")
+		fs.Float64Var(&settings.DedupeFPRate, "dedupe-fp-rate", 0, "Remember seen URLs in a Bloom filter with this false positive `rate` (0 for exact).")
+		fs.Int64Var(&settings.DedupeCapacity, "dedupe-capacity", 10000000, "Number of `URLs` to size the Bloom filter for.")
+		canonicalizeHelp := fmt.Sprintf("Comma-separated `rules` for recognising duplicate URLs.  Options: [%s]", strings.Join(canonicalizeRuleStrings[:], ", "))
+		fs.StringVar(&settings.Canonicalize, "canonicalize", strings.Join(defaultCanonicalizeRules, ","), canonicalizeHelp)
+		fs.Var(&settings.DropParams, "drop-param", "Query `parameters` to ignore when recognising duplicate URLs.")
+		fs.Var(&settings.HostWeights, "host-weight", "Serve `host=weight` more often than other hosts (default weight 1).")
+		fs.Int64Var(&settings.MaxBodySize, "max-body-size", 10*1024*1024, "Most `bytes` of each response body to read and measure (0 to not read bodies).")
+		fs.IntVar(&settings.MaxHostConns, "max-host-conns", 0, "Maximum concurrent `requests` to a single host (0 for unlimited).")
+		fs.Var(DurationFlag{&settings.MinHostInterval}, "min-host-interval", "Minimum time (as `duration`) between requests to a single host.")
+		fs.StringVar(&settings.QueueSpillDir, "queue-spill-dir", "", "`Directory` for queued tasks spilled to disk (default system temp dir).")
+		fs.IntVar(&settings.MaxDepth, "max-depth", 0, "Maximum `depth` of links and expansions from the starting URL (0 for unlimited).")
+		fs.IntVar(&settings.MaxScopeDepth, "max-scope-depth", 0, "Maximum number of path `segments` below the starting URL (0 for unlimited).")
+		fs.Int64Var(&settings.MaxRequests, "max-requests", 0, "Stop after this many HTTP `requests` (0 for unlimited).")
+		fs.Var(DurationFlag{&settings.MaxTime}, "max-time", "Stop the scan after this `duration` (0 for unlimited).")
+		fs.StringVar(&settings.CheckpointPath, "checkpoint", "", "Periodically save scan state to `file`.")
+		fs.Var(DurationFlag{&settings.CheckpointInterval}, "checkpoint-interval", "How often (as `duration`) to save scan state.")
+		fs.StringVar(&settings.ResumePath, "resume", "", "Resume a scan from a checkpoint `file`.")
+	}
+	if groups&FlagsWordlist != 0 {
+		fs.StringVar(&settings.WordlistPath, "wordlist", "", "Wordlist `filename` to use (default built-in)")
+		fs.BoolVar(&settings.MangleCases, "cases", false, "Modify the wordlist with alternate cases.")
+		fs.BoolVar(&settings.AddSlashes, "slashes", false, "Add slashes to paths to check for servers that don't redirect.")
+	}
+	if groups&FlagsEnumeration != 0 {
+		if groups&FlagsLegacy != 0 {
+			runModeHelp := fmt.Sprintf("Run `mode`. Options: [%s]", strings.Join(runModeStrings[:], ", "))
+			fs.Var(&settings.RunMode, "mode", runModeHelp)
+		} else {
+			runModeHelp := fmt.Sprintf("Run `mode`. Options: [%s]", strings.Join(runModeStrings[:RunModeLinkCheck], ", "))
+			fs.Var(ScanModeFlag{&settings.RunMode}, "mode", runModeHelp)
+		}
+		fs.BoolVar(&settings.ParseHTML, "html", true, "Parse HTML documents for links to follow.")
+		fs.Var(&settings.Extensions, "extensions", "List of `extensions` to mangle with.")
+		fs.BoolVar(&settings.Mangle, "mangle", true, "Mangle by adding extensions.")
+		fs.BoolVar(&settings.Soft404, "soft404", false, "Recognise and suppress soft 404s by requesting random names in each directory (off by default).  Costs 3 requests per directory for each extension, counted against -max-requests.")
+		fs.Var(&settings.OptionalHeader, "optional-header", "Headers to try sending one at a time.")
+	}
+	if groups&FlagsOutput != 0 {
+		if len(outputFormats) > 1 {
+			formatHelp := fmt.Sprintf("Output `format`.  Options: [%s]", strings.Join(outputFormats, ", "))
+			fs.StringVar(&settings.OutputFormat, "format", outputFormats[0], formatHelp)
+		}
+		fs.StringVar(&settings.OutputPath, "outfile", "", "Output `file`, defaults to stdout.")
+		fs.BoolVar(&settings.IncludeRedirects, "include-redirects", false, "Include redirects in reports.")
+	}
+}
 
-	settings.flagsSet = true
+// Load settings from the command line arguments, the environment and the
+// config file, in order of precedence.
+func (settings *ScanSettings) Load(args []string) error {
+	if err := settings.ParseArgs(args); err != nil {
+		return err
+	}
+	if err := settings.LoadEnvironment(); err != nil {
+		return err
+	}
+	return settings.LoadConfig()
+}
+
+// Load settings from the config file given with -config, or from the default
+// config files if none was given.  Settings from the command line or the
+// environment take precedence over those in the file.
+func (settings *ScanSettings) LoadConfig() error {
+	if settings.configPath != "" {
+		return settings.LoadFromConfigFile(settings.configPath)
+	}
+	if err := settings.LoadFromDefaultConfigFiles(); err != nil {
+		return err
+	}
+	if settings.profile != "" && settings.configPath == "" {
+		return fmt.Errorf("Profile %s requested but no config file found.", settings.profile)
+	}
+	return nil
 }
 
 // Load settings from the first file found in searchPaths
-func (settings *ScanSettings) LoadFromDefaultConfigFiles() {
+func (settings *ScanSettings) LoadFromDefaultConfigFiles() error {
 	for _, path := range defaultConfigPaths {
 		if info, err := os.Stat(path); err == nil {
 			if info.IsDir() {
 				continue
 			}
-			settings.LoadFromConfigFile(path)
-			return
+			return settings.LoadFromConfigFile(path)
 		}
 	}
+	return nil
 }
 
 // Load from the specified file
-func (settings *ScanSettings) LoadFromConfigFile(path string) {
+func (settings *ScanSettings) LoadFromConfigFile(path string) error {
 	settings.InitFlags()
-	// TODO: load
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	cf, err := parseConfig(fp, path)
+	if err != nil {
+		return err
+	}
+	if err := settings.applyConfig(cf); err != nil {
+		return err
+	}
+	logging.Logf(logging.LogInfo, "Loaded config file %s.", path)
+	if settings.profile != "" {
+		logging.Logf(logging.LogInfo, "Using profile %s.", settings.profile)
+	}
 	settings.configPath = path
+	return nil
 }
 
 // Parse command line flags into settings
-func (settings *ScanSettings) ParseFlags() {
+func (settings *ScanSettings) ParseFlags() error {
+	return settings.ParseArgs(os.Args[1:])
+}
+
+// Parse the given arguments into settings
+func (settings *ScanSettings) ParseArgs(args []string) error {
 	settings.InitFlags()
-	flag.Parse()
-	for i := 0; i < flag.NArg(); i++ {
-		settings.BaseURLs = append(settings.BaseURLs, flag.Arg(i))
+	flags := settings.flagSet()
+	restore := replaceDefaults(flags)
+	err := flags.Parse(args)
+	restore()
+	if err != nil {
+		return err
 	}
+	flags.Visit(func(f *flag.Flag) {
+		settings.setOrigin(flagDest(f.Name), SourceFlag, "")
+	})
+	// Commands without a scope use positional arguments for other purposes.
+	if settings.flagGroups&FlagsScan == 0 {
+		return nil
+	}
+	for i := 0; i < flags.NArg(); i++ {
+		settings.BaseURLs = append(settings.BaseURLs, flags.Arg(i))
+		settings.setOrigin("url", SourceFlag, "")
+	}
+	return nil
 }
 
 // Validate settings
 func (settings *ScanSettings) Validate() error {
 	flagError := func(str string) error {
-		os.Stderr.WriteString("Usage:\n")
-		flag.PrintDefaults()
+		settings.flagSet().Usage()
 		return errors.New(str)
 	}
-	if len(settings.BaseURLs) == 0 {
+	// Base URLs are loaded from the checkpoint when resuming
+	if len(settings.BaseURLs) == 0 && settings.ResumePath == "" {
 		return flagError("URL is required.")
 	}
+	if settings.MaxDepth < 0 || settings.MaxScopeDepth < 0 {
+		return flagError("Maximum depths may not be negative.")
+	}
+	if settings.MaxRequests < 0 || settings.MaxTime < 0 {
+		return flagError("Request and time limits may not be negative.")
+	}
+	if settings.DedupeFPRate < 0 || settings.DedupeFPRate >= 1 {
+		return flagError("Dedupe false positive rate must be at least 0 and below 1.")
+	}
+	if settings.DedupeFPRate > 0 && settings.DedupeCapacity <= 0 {
+		return flagError("Dedupe capacity must be positive.")
+	}
+	for _, rule := range strings.Split(settings.Canonicalize, ",") {
+		if !validCanonicalizeRule(strings.TrimSpace(rule)) {
+			return flagError(fmt.Sprintf("Unknown canonicalization rule: %s", rule))
+		}
+	}
+	if settings.MaxBodySize < 0 {
+		return flagError("Maximum body size may not be negative.")
+	}
+	if settings.MaxHostConns < 0 {
+		return flagError("Maximum host connections may not be negative.")
+	}
+	if settings.MinHostInterval < 0 {
+		return flagError("Minimum host interval may not be negative.")
+	}
+	if settings.Retries < 0 || settings.RetryBackoff < 0 || settings.RetryBudget < 0 {
+		return flagError("Retry settings may not be negative.")
+	}
+	if settings.Rate < 0 || settings.HostRate < 0 || settings.SleepTime < 0 {
+		return flagError("Rates and sleep time may not be negative.")
+	}
+	if settings.QueueMemory < 0 {
+		return flagError("Queue memory limit may not be negative.")
+	}
+	checkpointing := settings.CheckpointPath != "" || settings.ResumePath != ""
+	if checkpointing && settings.CheckpointInterval <= 0 {
+		return flagError("Checkpoint interval must be positive.")
+	}
 	return nil
 }
 
@@ -232,13 +547,64 @@ func (settings *ScanSettings) Validate() error {
 func (settings *ScanSettings) String() string {
 	flags := make([]string, 0)
 
-	flag.VisitAll(func(f *flag.Flag) {
-		flags = append(flags, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+	settings.flagSet().VisitAll(func(f *flag.Flag) {
+		flags = append(flags, fmt.Sprintf("-%s=%s", f.Name, printableValue(f)))
 	})
 
 	return strings.Join(flags, " ")
 }
 
+// Get the flag set for the command line.  Commands may add flags of their own.
+func (settings *ScanSettings) FlagSet() *flag.FlagSet {
+	settings.InitFlags()
+	return settings.flagSet()
+}
+
+// Get positional arguments left after parsing the command line.
+func (settings *ScanSettings) Args() []string {
+	return settings.flagSet().Args()
+}
+
+// Get the flag set for these settings
+func (settings *ScanSettings) flagSet() *flag.FlagSet {
+	if settings.flags == nil {
+		return flag.CommandLine
+	}
+	return settings.flags
+}
+
+// Get the flag set with every setting, for config files and the environment.
+func (settings *ScanSettings) configFlagSet() *flag.FlagSet {
+	settings.InitFlags()
+	return settings.allFlags
+}
+
+// Maximum requests per second in total, or 0 for unlimited.  A sleep time
+// is converted to the rate the workers would reach if requests took no time,
+// and the lower of that and Rate is used.
+func (settings *ScanSettings) RequestRate() float64 {
+	rate := settings.Rate
+	if settings.SleepTime > 0 && settings.Workers > 0 {
+		sleepRate := float64(settings.Workers) / settings.SleepTime.Seconds()
+		if rate == 0 || sleepRate < rate {
+			rate = sleepRate
+		}
+	}
+	return rate
+}
+
+// Minimum time between requests to a single host, the longer of
+// MinHostInterval and the interval for HostRate.
+func (settings *ScanSettings) HostInterval() time.Duration {
+	interval := settings.MinHostInterval
+	if settings.HostRate > 0 {
+		if d := time.Duration(float64(time.Second) / settings.HostRate); d > interval {
+			interval = d
+		}
+	}
+	return interval
+}
+
 // Convert BaseURL strings to URLs
 func (settings *ScanSettings) GetScopes() ([]*url.URL, error) {
 	scopes := make([]*url.URL, len(settings.BaseURLs))
@@ -26,6 +26,12 @@ func TestRobotsModeStrings(t *testing.T) {
 	}
 }
 
+func TestQueueOrderStrings(t *testing.T) {
+	if len(queueOrderStrings) != queueOrderMax {
+		t.Errorf("QueueOrderStrings != enum: %d vs %d", len(queueOrderStrings), queueOrderMax)
+	}
+}
+
 // Test some defaults
 func TestNewScanSettings(t *testing.T) {
 	ss := NewScanSettings()
@@ -89,6 +95,30 @@ func TestIntSliceFlag(t *testing.T) {
 	}
 }
 
+func TestHostWeightFlag(t *testing.T) {
+	f := HostWeightFlag{}
+	if f.String() != "" {
+		t.Error("Expected empty string for empty HostWeightFlag.")
+	}
+	if err := f.Set("Example.com=3,localhost:8080=1"); err != nil {
+		t.Errorf("Error when setting HostWeightFlag: %v", err)
+	}
+	if err := f.Set("other.com=2"); err != nil {
+		t.Errorf("Error when setting HostWeightFlag: %v", err)
+	}
+	if f["example.com"] != 3 || f["localhost:8080"] != 1 || f["other.com"] != 2 {
+		t.Errorf("Unexpected weights: %v", f)
+	}
+	if s := f.String(); s != "example.com=3,localhost:8080=1,other.com=2" {
+		t.Errorf("Unexpected string: %s", s)
+	}
+	for _, bad := range []string{"example.com", "=2", "example.com=0", "example.com=x"} {
+		if err := f.Set(bad); err == nil {
+			t.Errorf("Expected error when setting %q.", bad)
+		}
+	}
+}
+
 func TestDurationFlag_Empty(t *testing.T) {
 	f := DurationFlag{}
 	if f.String() != "" {
@@ -204,4 +234,52 @@ func TestScanSettings_Validate(t *testing.T) {
 	if err := ss.Validate(); err != nil {
 		t.Errorf("Expected no errors with BaseURLs.")
 	}
+	ss.Canonicalize = "path,query"
+	if err := ss.Validate(); err != nil {
+		t.Errorf("Expected no errors with canonicalization rules, got %v", err)
+	}
+	ss.Canonicalize = "path,bogus"
+	if err := ss.Validate(); err == nil {
+		t.Errorf("Expected error with unknown canonicalization rule.")
+	}
+	ss.Canonicalize = ""
+	ss.Retries = -1
+	if err := ss.Validate(); err == nil {
+		t.Errorf("Expected error with negative retries.")
+	}
+}
+
+func TestSettingSourceStrings(t *testing.T) {
+	if len(settingSourceStrings) != int(settingSourceMax) {
+		t.Errorf("settingSourceStrings != enum: %d vs %d", len(settingSourceStrings), settingSourceMax)
+	}
+}
+
+func TestScanSettings_RequestRate(t *testing.T) {
+	cases := []struct {
+		rate    float64
+		sleep   time.Duration
+		workers int
+		want    float64
+	}{
+		{0, 0, 4, 0},
+		{5, 0, 4, 5},
+		{0, time.Second, 4, 4},
+		{2, time.Second, 4, 2},
+		{10, 500 * time.Millisecond, 4, 8},
+	}
+	for _, c := range cases {
+		ss := &ScanSettings{Rate: c.rate, SleepTime: c.sleep, Workers: c.workers}
+		if got := ss.RequestRate(); got != c.want {
+			t.Errorf("RequestRate(rate=%v, sleep=%s, workers=%d): expected %v, got %v", c.rate, c.sleep, c.workers, c.want, got)
+		}
+	}
+	ss := &ScanSettings{HostRate: 4, MinHostInterval: 100 * time.Millisecond}
+	if got := ss.HostInterval(); got != 250*time.Millisecond {
+		t.Errorf("Expected host interval from rate, got %s", got)
+	}
+	ss.MinHostInterval = time.Second
+	if got := ss.HostInterval(); got != time.Second {
+		t.Errorf("Expected minimum host interval, got %s", got)
+	}
 }
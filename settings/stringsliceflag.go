@@ -38,6 +38,33 @@ func (f *StringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// Reset discards all values, including defaults.
+func (f *StringSliceFlag) Reset() {
+	*f = nil
+}
+
+// StringListFlag is a flag.Value that takes a repeated string and turns it
+// into a slice of strings.  Unlike StringSliceFlag, values are not split on
+// commas.
+type StringListFlag []string
+
+func (f *StringListFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, "\n")
+}
+
+func (f *StringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Reset discards all values, including defaults.
+func (f *StringListFlag) Reset() {
+	*f = nil
+}
+
 // StringSliceFileFlag is flag.Value that loads from a file into a wrapped
 // StringSliceFlag
 type StringSliceFileFlag struct {
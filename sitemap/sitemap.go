@@ -0,0 +1,173 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sitemap provides support for discovering and parsing sitemaps, as
+// described at https://www.sitemaps.org/protocol.html.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/logging"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+const (
+	// Sitemaps are limited to 50MB uncompressed by the protocol.
+	maxSitemapSize = 50 * 1024 * 1024
+	// Maximum number of sitemaps to load for one site, including the sitemaps
+	// listed in indexes.
+	MaxSitemaps = 1000
+)
+
+// Sitemap holds the URLs listed in a sitemap, or the sitemaps listed in a
+// sitemap index.
+type Sitemap struct {
+	URLs     []string
+	Sitemaps []string
+}
+
+type xmlLoc struct {
+	Loc string `xml:"loc"`
+}
+
+type xmlSitemap struct {
+	URLs     []xmlLoc `xml:"url"`
+	Sitemaps []xmlLoc `xml:"sitemap"`
+}
+
+// Parse a sitemap, which may be an XML urlset or sitemapindex, or a text file
+// with one URL per line.  Gzip-compressed sitemaps are decompressed.
+func Parse(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to decompress sitemap: %s", err.Error())
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(br, maxSitemapSize))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read sitemap: %s", err.Error())
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '<' {
+		return parseText(trimmed), nil
+	}
+	doc := &xmlSitemap{}
+	if err := xml.Unmarshal(trimmed, doc); err != nil {
+		return nil, fmt.Errorf("Unable to parse sitemap: %s", err.Error())
+	}
+	sm := &Sitemap{}
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			sm.URLs = append(sm.URLs, loc)
+		}
+	}
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sm.Sitemaps = append(sm.Sitemaps, loc)
+		}
+	}
+	return sm, nil
+}
+
+func parseText(data []byte) *Sitemap {
+	sm := &Sitemap{}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			sm.URLs = append(sm.URLs, string(line))
+		}
+	}
+	return sm
+}
+
+// Load and parse a single sitemap.
+func GetSitemap(u *url.URL, factory client.ClientFactory) (*Sitemap, error) {
+	resp, err := factory.Get().RequestURL(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("Unable to load sitemap %s: status %d", u, resp.StatusCode)
+	}
+	return Parse(resp.Body)
+}
+
+// Find the URLs listed in the sitemaps for a site.  The given sitemaps, such
+// as those listed in robots.txt, then /sitemap.xml are loaded, following
+// sitemap indexes up to MaxSitemaps sitemaps in total.  Only sitemaps for
+// which inScope returns true are loaded.  Sitemaps that can't be loaded are
+// logged and skipped.
+func GetURLsForSite(base *url.URL, sitemaps []string, factory client.ClientFactory, inScope func(*url.URL) bool) []*url.URL {
+	var pending []*url.URL
+	for _, s := range sitemaps {
+		if u, err := base.Parse(s); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to parse sitemap URL %s: %s", s, err)
+		} else {
+			pending = append(pending, u)
+		}
+	}
+	ref, _ := url.Parse("/sitemap.xml")
+	pending = append(pending, base.ResolveReference(ref))
+	visited := make(map[string]bool)
+	seen := make(map[string]bool)
+	var results []*url.URL
+	loaded := 0
+	for len(pending) > 0 {
+		u := pending[0]
+		pending = pending[1:]
+		if visited[u.String()] {
+			continue
+		}
+		visited[u.String()] = true
+		if !inScope(u) {
+			logging.Logf(logging.LogInfo, "Not loading sitemap %s, out of scope.", u)
+			continue
+		}
+		if loaded >= MaxSitemaps {
+			logging.Logf(logging.LogWarning, "Not loading more than %d sitemaps for %s.", MaxSitemaps, base)
+			break
+		}
+		loaded++
+		logging.Logf(logging.LogDebug, "Loading sitemap %s", u)
+		sm, err := GetSitemap(u, factory)
+		if err != nil {
+			logging.Logf(logging.LogInfo, "Unable to get sitemap: %s", err)
+			continue
+		}
+		for _, s := range sm.Sitemaps {
+			if child, err := u.Parse(s); err == nil {
+				pending = append(pending, child)
+			}
+		}
+		for _, s := range sm.URLs {
+			if target, err := u.Parse(s); err == nil && !seen[target.String()] {
+				seen[target.String()] = true
+				results = append(results, target)
+			}
+		}
+	}
+	return results
+}
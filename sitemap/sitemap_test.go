@@ -0,0 +1,154 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/Matir/webborer/client"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>http://example.com/a</loc><lastmod>2018-01-01</lastmod></url>
+  <url><loc>
+    http://example.com/b?x=1&amp;y=2
+  </loc></url>
+  <url><loc></loc></url>
+</urlset>`
+
+const testIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/pages.xml.gz</loc></sitemap>
+  <sitemap><loc>/posts.txt</loc></sitemap>
+</sitemapindex>`
+
+func gzipString(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("Unable to compress: %v", err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"plain": []byte(testURLSet),
+		"gzip":  gzipString(t, testURLSet),
+	} {
+		sm, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: unable to parse: %v", name, err)
+		}
+		expected := []string{"http://example.com/a", "http://example.com/b?x=1&y=2"}
+		if len(sm.URLs) != len(expected) || sm.URLs[0] != expected[0] || sm.URLs[1] != expected[1] {
+			t.Errorf("%s: expected %v, got %v", name, expected, sm.URLs)
+		}
+		if len(sm.Sitemaps) != 0 {
+			t.Errorf("%s: expected no sitemaps, got %v", name, sm.Sitemaps)
+		}
+	}
+}
+
+func TestParse_Index(t *testing.T) {
+	sm, err := Parse(strings.NewReader(testIndex))
+	if err != nil {
+		t.Fatalf("Unable to parse: %v", err)
+	}
+	if len(sm.Sitemaps) != 2 || sm.Sitemaps[0] != "/pages.xml.gz" || len(sm.URLs) != 0 {
+		t.Errorf("Unexpected index contents: %+v", sm)
+	}
+}
+
+func TestParse_Text(t *testing.T) {
+	sm, err := Parse(strings.NewReader("http://example.com/a\r\n\nhttp://example.com/b\n"))
+	if err != nil {
+		t.Fatalf("Unable to parse: %v", err)
+	}
+	if len(sm.URLs) != 2 || sm.URLs[1] != "http://example.com/b" {
+		t.Errorf("Unexpected text sitemap contents: %v", sm.URLs)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<urlset><url>")); err == nil {
+		t.Error("Expected error for truncated XML.")
+	}
+	if _, err := Parse(bytes.NewReader([]byte{0x1f, 0x8b, 0x00})); err == nil {
+		t.Error("Expected error for bad gzip data.")
+	}
+}
+
+func TestGetURLsForSite(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/listed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testIndex))
+	})
+	mux.HandleFunc("/pages.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(gzipString(t, `<urlset><url><loc>/p1</loc></url><url><loc>/p2</loc></url></urlset>`))
+	})
+	mux.HandleFunc("/posts.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("/p2\n/posts/1\n"))
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		// Loops back to the index, which is only loaded once
+		w.Write([]byte(`<sitemapindex><sitemap><loc>/listed.xml</loc></sitemap><sitemap><loc>/private/child.xml</loc></sitemap></sitemapindex>`))
+	})
+	var mu sync.Mutex
+	var outOfScope []string
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		outOfScope = append(outOfScope, r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte("/private/page\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	factory, err := client.NewProxyClientFactory(nil, time.Second, "test")
+	if err != nil {
+		t.Fatalf("Unable to build factory: %v", err)
+	}
+	base, _ := url.Parse(srv.URL + "/app/")
+	inScope := func(u *url.URL) bool {
+		return !strings.HasPrefix(u.Path, "/private/")
+	}
+	found := GetURLsForSite(base, []string{"/listed.xml", "/missing.xml", "/private/listed.txt"}, factory, inScope)
+	var paths []string
+	for _, u := range found {
+		paths = append(paths, u.Path)
+	}
+	sort.Strings(paths)
+	expected := []string{"/p1", "/p2", "/posts/1"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(outOfScope) != 0 {
+		t.Errorf("Expected out of scope sitemaps not to be loaded, got %v", outOfScope)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stats collects live statistics about a scan.
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Length of the window for recent rates, in seconds
+const windowSeconds = 10
+
+// Stats collects request statistics.  It is safe for concurrent use.
+type Stats struct {
+	start    time.Time
+	requests int64
+	errors   int64
+	codes    map[int]int64
+	bytes    int64
+	latency  time.Duration
+	// Work counter totals
+	done  int64
+	total int64
+	// Requests and errors by second, for recent rates
+	window [windowSeconds]bucket
+	// Time source, replaced in tests
+	now func() time.Time
+	sync.Mutex
+}
+
+type bucket struct {
+	second   int64
+	requests int64
+	errors   int64
+}
+
+// Snapshot is the state of a Stats at one time.
+type Snapshot struct {
+	Elapsed  time.Duration
+	Requests int64
+	// Requests that did not get a response
+	Errors int64
+	// Number of responses by status code
+	Codes map[int]int64
+	// Response body bytes
+	Bytes int64
+	// Mean time to response headers
+	AvgLatency time.Duration
+	// Requests per second over the last few seconds
+	Rate float64
+	// Fraction of requests failing over the last few seconds
+	ErrorRate float64
+	// Work done and to be done
+	Done  int64
+	Total int64
+	// Estimated time to finish, 0 if unknown
+	ETA time.Duration
+}
+
+// Create a Stats starting now.
+func New() *Stats {
+	return newStats(time.Now)
+}
+
+func newStats(now func() time.Time) *Stats {
+	return &Stats{
+		start: now(),
+		codes: make(map[int]int64),
+		now:   now,
+	}
+}
+
+// Restart the clock for the elapsed time and rates.
+func (s *Stats) Start() {
+	s.Lock()
+	defer s.Unlock()
+	s.start = s.now()
+}
+
+// Record a request.  A nil Stats records nothing.
+func (s *Stats) Record(code int, bytes int64, latency time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.requests++
+	b := s.bucket()
+	b.requests++
+	if err != nil {
+		s.errors++
+		b.errors++
+	}
+	if code != 0 {
+		s.codes[code]++
+	}
+	if bytes > 0 {
+		s.bytes += bytes
+	}
+	s.latency += latency
+}
+
+// Record the work counter totals, for the ETA.
+func (s *Stats) SetProgress(done, total int64) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.done, s.total = done, total
+}
+
+// Get the bucket for the current second.  Must hold the lock.
+func (s *Stats) bucket() *bucket {
+	sec := s.now().Unix()
+	b := &s.window[sec%windowSeconds]
+	if b.second != sec {
+		*b = bucket{second: sec}
+	}
+	return b
+}
+
+// Get the current statistics.
+func (s *Stats) Snapshot() Snapshot {
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	snap := Snapshot{
+		Elapsed:  now.Sub(s.start),
+		Requests: s.requests,
+		Errors:   s.errors,
+		Codes:    make(map[int]int64, len(s.codes)),
+		Bytes:    s.bytes,
+		Done:     s.done,
+		Total:    s.total,
+	}
+	for k, v := range s.codes {
+		snap.Codes[k] = v
+	}
+	if s.requests > 0 {
+		snap.AvgLatency = s.latency / time.Duration(s.requests)
+	}
+
+	// Recent rates, from the complete seconds in the window and the current one
+	var requests, errors int64
+	sec := now.Unix()
+	for _, b := range s.window {
+		if b.second > sec-windowSeconds && b.second <= sec {
+			requests += b.requests
+			errors += b.errors
+		}
+	}
+	span := snap.Elapsed.Seconds()
+	if span > windowSeconds {
+		span = windowSeconds
+	}
+	if span > 0 {
+		snap.Rate = float64(requests) / span
+	}
+	if requests > 0 {
+		snap.ErrorRate = float64(errors) / float64(requests)
+	}
+
+	if s.done > 0 && s.total > s.done {
+		perTask := snap.Elapsed / time.Duration(s.done)
+		snap.ETA = perTask * time.Duration(s.total-s.done)
+	}
+	return snap
+}
+
+// A short summary for the progress display.
+func (s Snapshot) String() string {
+	str := fmt.Sprintf("%.1f req/s, %.1f%% errors, %s avg, %s",
+		s.Rate, s.ErrorRate*100, roundDuration(s.AvgLatency), FormatBytes(s.Bytes))
+	if s.ETA > 0 {
+		str += ", ETA " + roundDuration(s.ETA).String()
+	}
+	return str
+}
+
+// A multi-line summary for the end of a scan.
+func (s Snapshot) Summary() string {
+	lines := make([]string, 0)
+	secs := s.Elapsed.Seconds()
+	rate := 0.0
+	if secs > 0 {
+		rate = float64(s.Requests) / secs
+	}
+	lines = append(lines, fmt.Sprintf("Requests:  %d in %s (%.1f/s)", s.Requests, roundDuration(s.Elapsed), rate))
+	errorPct := 0.0
+	if s.Requests > 0 {
+		errorPct = float64(s.Errors) * 100 / float64(s.Requests)
+	}
+	lines = append(lines, fmt.Sprintf("Errors:    %d (%.1f%%)", s.Errors, errorPct))
+	lines = append(lines, fmt.Sprintf("Latency:   %s average", roundDuration(s.AvgLatency)))
+	lines = append(lines, fmt.Sprintf("Received:  %s", FormatBytes(s.Bytes)))
+	codes := make([]int, 0, len(s.Codes))
+	for c := range s.Codes {
+		codes = append(codes, c)
+	}
+	sort.Ints(codes)
+	for _, c := range codes {
+		lines = append(lines, fmt.Sprintf("  %d:     %d", c, s.Codes[c]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Format a byte count with a binary unit.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Round a duration for display.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second)
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond)
+	default:
+		return d.Round(time.Millisecond)
+	}
+}
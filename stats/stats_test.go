@@ -0,0 +1,115 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func TestStats_Snapshot(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	s := newStats(clock.now)
+	s.Record(200, 1024, 100*time.Millisecond, nil)
+	s.Record(404, 100, 300*time.Millisecond, nil)
+	clock.t = clock.t.Add(time.Second)
+	s.Record(0, 0, 200*time.Millisecond, errors.New("timeout"))
+	s.Record(200, 2048, 200*time.Millisecond, nil)
+	s.SetProgress(25, 100)
+	clock.t = clock.t.Add(time.Second)
+
+	snap := s.Snapshot()
+	if snap.Requests != 4 || snap.Errors != 1 {
+		t.Errorf("Expected 4 requests and 1 error, got %d and %d", snap.Requests, snap.Errors)
+	}
+	if snap.Codes[200] != 2 || snap.Codes[404] != 1 || len(snap.Codes) != 2 {
+		t.Errorf("Unexpected status codes: %v", snap.Codes)
+	}
+	if snap.Bytes != 3172 {
+		t.Errorf("Expected 3172 bytes, got %d", snap.Bytes)
+	}
+	if snap.AvgLatency != 200*time.Millisecond {
+		t.Errorf("Expected 200ms average latency, got %s", snap.AvgLatency)
+	}
+	if snap.Rate != 2 {
+		t.Errorf("Expected 2 requests/s, got %f", snap.Rate)
+	}
+	if snap.ErrorRate != 0.25 {
+		t.Errorf("Expected error rate of 0.25, got %f", snap.ErrorRate)
+	}
+	if snap.ETA != 6*time.Second {
+		t.Errorf("Expected ETA of 6s, got %s", snap.ETA)
+	}
+	if str := snap.String(); str != "2.0 req/s, 25.0% errors, 200ms avg, 3.1 KiB, ETA 6s" {
+		t.Errorf("Unexpected string: %s", str)
+	}
+	summary := snap.Summary()
+	for _, want := range []string{"Requests:  4 in 2s (2.0/s)", "Errors:    1 (25.0%)", "  404:     1"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %q in summary:\n%s", want, summary)
+		}
+	}
+}
+
+func TestStats_RecentRate(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	s := newStats(clock.now)
+	for i := 0; i < 50; i++ {
+		s.Record(0, 0, 0, errors.New("refused"))
+	}
+	// Old requests leave the window
+	clock.t = clock.t.Add(30 * time.Second)
+	for i := 0; i < 20; i++ {
+		s.Record(200, 0, 0, nil)
+	}
+	snap := s.Snapshot()
+	if snap.Rate != 2 {
+		t.Errorf("Expected 2 requests/s, got %f", snap.Rate)
+	}
+	if snap.ErrorRate != 0 {
+		t.Errorf("Expected no recent errors, got %f", snap.ErrorRate)
+	}
+}
+
+func TestStats_Nil(t *testing.T) {
+	var s *Stats
+	s.Record(200, 1, time.Second, nil)
+	s.SetProgress(1, 2)
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range cases {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d): expected %s, got %s", n, want, got)
+		}
+	}
+}
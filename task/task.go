@@ -15,16 +15,43 @@
 package task
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
 )
 
+// How a task was discovered
+type TaskSource int
+
+const (
+	// Starting URLs
+	SourceSeed = TaskSource(iota)
+	// Paths listed in robots.txt
+	SourceRobots
+	// Targets of redirects
+	SourceRedirect
+	// Links in HTML pages
+	SourceHTML
+	// Guesses from the wordlist
+	SourceWordlist
+	// URLs listed in sitemaps
+	SourceSitemap
+)
+
 type Task struct {
 	URL    *url.URL
 	Host   string
 	Header http.Header
+	// Number of steps (expansions or links) from the seed URL
+	Depth int
+	// How the task was discovered
+	Source TaskSource
+	// Position in the wordlist of the word that produced the task
+	Rank int
+	// Key of the task accepted into the WorkQueue that this was expanded from
+	Origin string
 
 	// Mutex to protect map & data structures
 	sync.Mutex
@@ -52,12 +79,16 @@ func (t *Task) Copy() *Task {
 	defer t.Unlock()
 	tmpU := *t.URL
 	newT := &Task{
-		Host: t.Host,
-		URL:  &tmpU,
+		Host:   t.Host,
+		URL:    &tmpU,
+		Depth:  t.Depth,
+		Source: t.Source,
+		Rank:   t.Rank,
+		Origin: t.Origin,
 	}
 	newT.Header = make(http.Header)
 	for k, v := range t.Header {
-		newT.Header[k] = v[:] // Need to copy the slice
+		newT.Header[k] = append([]string(nil), v...) // Need to copy the slice
 	}
 	return newT
 }
@@ -65,3 +96,49 @@ func (t *Task) Copy() *Task {
 func SetDefaultHeader(header http.Header) {
 	defaultHeader = header
 }
+
+// Serialized form of a Task.
+type jsonTask struct {
+	URL    string      `json:"url"`
+	Host   string      `json:"host,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Depth  int         `json:"depth,omitempty"`
+	Source TaskSource  `json:"source,omitempty"`
+	Rank   int         `json:"rank,omitempty"`
+	Origin string      `json:"origin,omitempty"`
+}
+
+func (t *Task) MarshalJSON() ([]byte, error) {
+	t.Lock()
+	defer t.Unlock()
+	return json.Marshal(&jsonTask{
+		URL:    t.URL.String(),
+		Host:   t.Host,
+		Header: t.Header,
+		Depth:  t.Depth,
+		Source: t.Source,
+		Rank:   t.Rank,
+		Origin: t.Origin,
+	})
+}
+
+func (t *Task) UnmarshalJSON(data []byte) error {
+	jt := &jsonTask{}
+	if err := json.Unmarshal(data, jt); err != nil {
+		return err
+	}
+	u, err := url.Parse(jt.URL)
+	if err != nil {
+		return err
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.URL = u
+	t.Host = jt.Host
+	t.Header = jt.Header
+	t.Depth = jt.Depth
+	t.Source = jt.Source
+	t.Rank = jt.Rank
+	t.Origin = jt.Origin
+	return nil
+}
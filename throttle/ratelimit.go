@@ -0,0 +1,276 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttle
+
+import (
+	"context"
+	"github.com/Matir/webborer/logging"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// First interval used when a host starts throttling us
+	minBackoff = 250 * time.Millisecond
+	// Longest interval backoff will reach
+	maxBackoff = time.Minute
+	// Longest pause honoured from a Retry-After header
+	maxRetryAfter = 5 * time.Minute
+	// Responses without throttling before the backoff is halved
+	recoverAfter = 10
+)
+
+// RateLimiter spaces out requests, shared by all of the clients in a scan.
+// Requests start at most at a global rate, and requests to each host start at
+// least an interval apart.  Hosts that answer 429 or 503 are backed off, and
+// ramped back up as they recover.  A nil RateLimiter allows everything.
+type RateLimiter struct {
+	// Interval between any two requests, 0 for unlimited
+	globalInterval time.Duration
+	// Earliest start of the next request
+	nextGlobal time.Time
+	// Interval between requests to hosts without their own
+	hostInterval time.Duration
+	// State by lower case host
+	hosts map[string]*hostState
+	// Closed to release waiting requests
+	stop     chan struct{}
+	stopOnce sync.Once
+	now      func() time.Time
+	sync.Mutex
+}
+
+type hostState struct {
+	// Interval for this host, such as from a robots.txt Crawl-delay
+	interval time.Duration
+	// Interval added by backing off, 0 when not backing off
+	backoff time.Duration
+	// Responses without throttling since backoff last changed
+	recovered int
+	// Earliest start of the next request
+	next time.Time
+	// Start of the next request after a Retry-After
+	pausedUntil time.Time
+}
+
+// Create a RateLimiter allowing rate requests per second in total, with at
+// least hostInterval between requests to each host.  Either may be 0 for no
+// limit.
+func NewRateLimiter(rate float64, hostInterval time.Duration) *RateLimiter {
+	l := &RateLimiter{
+		hostInterval: hostInterval,
+		hosts:        make(map[string]*hostState),
+		stop:         make(chan struct{}),
+		now:          time.Now,
+	}
+	if rate > 0 {
+		l.globalInterval = time.Duration(float64(time.Second) / rate)
+	}
+	return l
+}
+
+func (l *RateLimiter) host(host string) *hostState {
+	host = HostKey(host)
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// Effective interval for a host, the longest of its own, the default and the
+// backoff.
+func (l *RateLimiter) interval(h *hostState) time.Duration {
+	d := l.hostInterval
+	if h.interval > d {
+		d = h.interval
+	}
+	if h.backoff > d {
+		d = h.backoff
+	}
+	return d
+}
+
+// Set the interval between requests to host.  The default interval is used
+// if it is longer.
+func (l *RateLimiter) SetInterval(host string, interval time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.host(host).interval = interval
+}
+
+// Get the current interval between requests to host, including any backoff.
+func (l *RateLimiter) Interval(host string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	return l.interval(l.host(host))
+}
+
+// Wait until a request to host may start.  The host's turn is reserved
+// first, then a slot in the global rate, so a paused host does not hold up
+// the others.  Returns false if ctx is done or the limiter is stopped first.
+func (l *RateLimiter) Wait(ctx context.Context, host string) bool {
+	if l == nil {
+		return ctx.Err() == nil
+	}
+	l.Lock()
+	h := l.host(host)
+	start := l.now()
+	if h.next.After(start) {
+		start = h.next
+	}
+	if h.pausedUntil.After(start) {
+		start = h.pausedUntil
+	}
+	h.next = start.Add(l.interval(h))
+	l.Unlock()
+	if !l.sleepUntil(ctx, start) {
+		return false
+	}
+
+	if l.globalInterval <= 0 {
+		return true
+	}
+	l.Lock()
+	start = l.now()
+	if l.nextGlobal.After(start) {
+		start = l.nextGlobal
+	}
+	l.nextGlobal = start.Add(l.globalInterval)
+	l.Unlock()
+	return l.sleepUntil(ctx, start)
+}
+
+func (l *RateLimiter) sleepUntil(ctx context.Context, t time.Time) bool {
+	delay := t.Sub(l.now())
+	if delay <= 0 {
+		return ctx.Err() == nil && !l.stopped()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-l.stop:
+		return false
+	}
+}
+
+// Adjust the rate for host after a response.  A 429 or 503 doubles the
+// interval between requests, and a Retry-After header pauses the host;
+// other responses gradually remove the backoff.
+func (l *RateLimiter) Observe(host string, code int, retryAfter string) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	h := l.host(host)
+	now := l.now()
+	if code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+		if h.backoff == 0 {
+			return
+		}
+		h.recovered++
+		if h.recovered < recoverAfter {
+			return
+		}
+		h.recovered = 0
+		h.backoff /= 2
+		if h.backoff < minBackoff {
+			h.backoff = 0
+			logging.Logf(logging.LogInfo, "Host %s recovered, no longer backing off.", host)
+		}
+		return
+	}
+	h.recovered = 0
+	switch {
+	case h.backoff == 0:
+		h.backoff = minBackoff
+		if d := l.interval(h); d > h.backoff {
+			h.backoff = d
+		}
+		h.backoff *= 2
+	case h.backoff < maxBackoff:
+		h.backoff *= 2
+	}
+	if h.backoff > maxBackoff {
+		h.backoff = maxBackoff
+	}
+	if wait, ok := ParseRetryAfter(retryAfter, now); ok {
+		if wait > maxRetryAfter {
+			logging.Logf(logging.LogWarning, "Host %s asked for a pause of %s, only pausing %s.", host, wait, maxRetryAfter)
+			wait = maxRetryAfter
+		}
+		if until := now.Add(wait); until.After(h.pausedUntil) {
+			h.pausedUntil = until
+		}
+	}
+	if next := now.Add(h.backoff); next.After(h.next) {
+		h.next = next
+	}
+	logging.Logf(logging.LogInfo, "Host %s returned %d, backing off to one request every %s.", host, code, h.backoff)
+}
+
+// Release all waiting requests, and fail any later calls to Wait.
+func (l *RateLimiter) Stop() {
+	if l == nil {
+		return
+	}
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+}
+
+func (l *RateLimiter) stopped() bool {
+	select {
+	case <-l.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse a Retry-After header, given as seconds or an HTTP date, into the
+// time to wait from now.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
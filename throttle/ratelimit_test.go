@@ -0,0 +1,205 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttle
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Nil(t *testing.T) {
+	var l *RateLimiter
+	if !l.Wait(context.Background(), "localhost") {
+		t.Error("Expected nil limiter to allow requests.")
+	}
+	if l.Interval("localhost") != 0 {
+		t.Error("Expected no interval for nil limiter.")
+	}
+	l.Observe("localhost", 429, "10")
+	l.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if l.Wait(ctx, "localhost") {
+		t.Error("Expected nil limiter to refuse requests once ctx is done.")
+	}
+}
+
+// Start n requests to each host at once, returning the sorted start times.
+func waitAll(t *testing.T, l *RateLimiter, n int, hosts ...string) []time.Time {
+	var mu sync.Mutex
+	var starts []time.Time
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(host string) {
+				defer wg.Done()
+				if !l.Wait(context.Background(), host) {
+					t.Error("Wait failed.")
+				}
+				mu.Lock()
+				starts = append(starts, time.Now())
+				mu.Unlock()
+			}(host)
+		}
+	}
+	wg.Wait()
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+func checkGaps(t *testing.T, starts []time.Time, interval time.Duration) {
+	for i := 1; i < len(starts); i++ {
+		// Allow for timer granularity
+		if gap := starts[i].Sub(starts[i-1]); gap < interval-5*time.Millisecond {
+			t.Errorf("Requests %d and %d only %s apart", i-1, i, gap)
+		}
+	}
+}
+
+func TestRateLimiter_HostInterval(t *testing.T) {
+	interval := 30 * time.Millisecond
+	l := NewRateLimiter(0, 0)
+	l.SetInterval("LocalHost", interval)
+	if l.Interval("localhost") != interval || l.Interval("example.com") != 0 {
+		t.Fatalf("Unexpected intervals: %s, %s", l.Interval("localhost"), l.Interval("example.com"))
+	}
+	// Other hosts are not delayed
+	begin := time.Now()
+	waitAll(t, l, 4, "example.com")
+	if time.Since(begin) > interval {
+		t.Error("Expected no wait for another host.")
+	}
+	checkGaps(t, waitAll(t, l, 4, "localhost"), interval)
+}
+
+func TestRateLimiter_DefaultInterval(t *testing.T) {
+	l := NewRateLimiter(0, time.Second)
+	l.SetInterval("short", time.Millisecond)
+	l.SetInterval("long", time.Minute)
+	if l.Interval("short") != time.Second || l.Interval("long") != time.Minute || l.Interval("other") != time.Second {
+		t.Errorf("Unexpected intervals: %s, %s, %s", l.Interval("short"), l.Interval("long"), l.Interval("other"))
+	}
+}
+
+func TestRateLimiter_GlobalRate(t *testing.T) {
+	l := NewRateLimiter(40, 0)
+	checkGaps(t, waitAll(t, l, 2, "a", "b", "c"), 25*time.Millisecond)
+}
+
+func TestRateLimiter_Backoff(t *testing.T) {
+	l := NewRateLimiter(0, 100*time.Millisecond)
+	l.Observe("localhost", 200, "")
+	if d := l.Interval("localhost"); d != 100*time.Millisecond {
+		t.Fatalf("Expected no backoff after 200, got %s", d)
+	}
+	l.Observe("localhost", 503, "")
+	if d := l.Interval("localhost"); d != 2*minBackoff {
+		t.Errorf("Expected backoff to %s, got %s", 2*minBackoff, d)
+	}
+	for i := 0; i < 20; i++ {
+		l.Observe("localhost", 429, "")
+	}
+	if d := l.Interval("localhost"); d != maxBackoff {
+		t.Errorf("Expected backoff capped at %s, got %s", maxBackoff, d)
+	}
+	if d := l.Interval("other"); d != 100*time.Millisecond {
+		t.Errorf("Expected other hosts unaffected, got %s", d)
+	}
+	// Recovers in steps
+	for i := 0; i < recoverAfter; i++ {
+		l.Observe("localhost", 200, "")
+	}
+	if d := l.Interval("localhost"); d != maxBackoff/2 {
+		t.Errorf("Expected backoff halved to %s, got %s", maxBackoff/2, d)
+	}
+	for i := 0; i < recoverAfter*20; i++ {
+		l.Observe("localhost", 404, "")
+	}
+	if d := l.Interval("localhost"); d != 100*time.Millisecond {
+		t.Errorf("Expected full recovery, got %s", d)
+	}
+}
+
+func TestRateLimiter_RetryAfter(t *testing.T) {
+	l := NewRateLimiter(0, 0)
+	l.Observe("localhost", 429, "120")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if l.Wait(ctx, "localhost") {
+		t.Error("Expected host to be paused by Retry-After.")
+	}
+	if !l.Wait(context.Background(), "example.com") {
+		t.Error("Expected other hosts not to be paused.")
+	}
+}
+
+func TestRateLimiter_Stop(t *testing.T) {
+	l := NewRateLimiter(0, time.Hour)
+	if !l.Wait(context.Background(), "localhost") {
+		t.Fatal("Expected first request not to wait.")
+	}
+	done := make(chan bool)
+	go func() {
+		done <- l.Wait(context.Background(), "localhost")
+	}()
+	l.Stop()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Error("Expected Wait to fail once stopped.")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not release waiting request.")
+	}
+	if l.Wait(context.Background(), "example.com") {
+		t.Error("Expected Wait to fail after Stop.")
+	}
+}
+
+func TestRateLimiter_Cancelled(t *testing.T) {
+	l := NewRateLimiter(0, 0)
+	defer l.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if l.Wait(ctx, "localhost") {
+		t.Error("Expected Wait to fail once ctx is done, even without a delay.")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2018 00:01:00 GMT", time.Minute, true},
+		{"Sun, 31 Dec 2017 00:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		wait, ok := ParseRetryAfter(c.value, now)
+		if wait != c.wait || ok != c.ok {
+			t.Errorf("ParseRetryAfter(%q): expected %s, %v, got %s, %v", c.value, c.wait, c.ok, wait, ok)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package throttle limits how hard a scan hits each host.
+package throttle
+
+import (
+	"strings"
+	"sync"
+)
+
+// Key for a host in the limiters and the queue.  Host names are compared
+// without regard to case.
+func HostKey(host string) string {
+	return strings.ToLower(host)
+}
+
+// HostLimiter limits the number of concurrent requests to each host.  A nil
+// HostLimiter allows everything.
+type HostLimiter struct {
+	limit int
+	// Requests in progress by host
+	active map[string]int
+	// Signalled when a slot is released
+	released chan struct{}
+	sync.Mutex
+}
+
+// Create a HostLimiter allowing limit concurrent requests per host.  Returns
+// nil if limit is 0.
+func NewHostLimiter(limit int) *HostLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &HostLimiter{
+		limit:    limit,
+		active:   make(map[string]int),
+		released: make(chan struct{}, 1),
+	}
+}
+
+// Take a slot for host if one is free, without waiting.  Each successful
+// TryAcquire must be followed by a Release.
+func (l *HostLimiter) TryAcquire(host string) bool {
+	if l == nil {
+		return true
+	}
+	host = HostKey(host)
+	l.Lock()
+	defer l.Unlock()
+	if l.active[host] >= l.limit {
+		return false
+	}
+	l.active[host]++
+	return true
+}
+
+// Release a slot for host.
+func (l *HostLimiter) Release(host string) {
+	if l == nil {
+		return
+	}
+	host = HostKey(host)
+	l.Lock()
+	if l.active[host] > 0 {
+		l.active[host]--
+	}
+	l.Unlock()
+	select {
+	case l.released <- struct{}{}:
+	default:
+	}
+}
+
+// Check if host has no free slots.
+func (l *HostLimiter) Full(host string) bool {
+	return l != nil && l.Active(host) >= l.limit
+}
+
+// Channel signalled after slots are released, or nil for a nil HostLimiter.
+func (l *HostLimiter) Released() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+	return l.released
+}
+
+// Number of requests in progress to host.
+func (l *HostLimiter) Active(host string) int {
+	if l == nil {
+		return 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	return l.active[HostKey(host)]
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttle
+
+import (
+	"testing"
+)
+
+func TestHostLimiter_Nil(t *testing.T) {
+	l := NewHostLimiter(0)
+	if l != nil {
+		t.Fatal("Expected nil limiter for no limit.")
+	}
+	if !l.TryAcquire("localhost") || l.Full("localhost") {
+		t.Error("Expected nil limiter to allow requests.")
+	}
+	l.Release("localhost")
+	if l.Released() != nil {
+		t.Error("Expected nil limiter never to signal.")
+	}
+}
+
+func TestHostLimiter_Limit(t *testing.T) {
+	l := NewHostLimiter(2)
+	for i := 0; i < 2; i++ {
+		if !l.TryAcquire("localhost") {
+			t.Fatalf("TryAcquire %d failed.", i)
+		}
+	}
+	if l.TryAcquire("LocalHost") || !l.Full("localhost") {
+		t.Error("Expected host to be full.")
+	}
+	// Other hosts are not affected
+	if !l.TryAcquire("example.com") {
+		t.Error("TryAcquire failed for second host.")
+	}
+	l.Release("localhost")
+	select {
+	case <-l.Released():
+	default:
+		t.Error("Expected release to be signalled.")
+	}
+	if l.Full("localhost") || l.Active("localhost") != 1 {
+		t.Errorf("Expected a free slot, got %d active", l.Active("localhost"))
+	}
+}
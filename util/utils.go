@@ -144,18 +144,13 @@ func EnableCPUProfiling() func() {
 		logging.Logf(logging.LogError, "Unable to open webborer.prof for profiling: %v", err)
 	} else {
 		pprof.StartCPUProfile(profFile)
-		sigintChan := make(chan os.Signal, 1)
-		signal.Notify(sigintChan, os.Interrupt)
+		// Interrupted scans finish normally, so the profile is written when
+		// the returned function is called.
 		cancelFunc := func() {
 			logging.Logf(logging.LogWarning, "Stopping profiling...")
 			pprof.StopCPUProfile()
-			signal.Stop(sigintChan)
+			profFile.Close()
 		}
-		// Gracefully handle Ctrl+C when profiling.
-		go func() {
-			<-sigintChan
-			cancelFunc()
-		}()
 		return cancelFunc
 	}
 	return nil
@@ -15,7 +15,10 @@
 package util
 
 import (
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -211,6 +214,25 @@ func TestEnableStackTraces(t *testing.T) {
 }
 
 func TestEnableCPUProfiling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unable to get working dir: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Unable to change dir: %v", err)
+	}
+	defer os.Chdir(wd)
 	cancel := EnableCPUProfiling()
+	if cancel == nil {
+		t.Fatal("Expected a function to stop profiling.")
+	}
 	cancel()
+	if _, err := os.Stat(filepath.Join(dir, "webborer.prof")); err != nil {
+		t.Errorf("Expected profile to be written: %v", err)
+	}
 }
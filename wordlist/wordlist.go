@@ -67,6 +67,9 @@ func ReadWordlist(rdr io.Reader) ([]string, error) {
 }
 
 // Loads a built-in wordlist for basic scans.
+// Names of the built-in wordlists.
+var BuiltinWordlists = []string{"default", "short"}
+
 func LoadBuiltinWordlist(which string) ([]string, error) {
 	switch which {
 	case "default":
@@ -67,3 +67,13 @@ func TestLoadWordlist_Default(t *testing.T) {
 		t.Errorf("Expected wordlist on return, got nil.")
 	}
 }
+
+func TestLoadBuiltinWordlist_All(t *testing.T) {
+	for _, name := range BuiltinWordlists {
+		if wl, err := LoadBuiltinWordlist(name); err != nil {
+			t.Errorf("Unable to load builtin wordlist %s: %v", name, err)
+		} else if len(wl) == 0 {
+			t.Errorf("Builtin wordlist %s is empty.", name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/Matir/webborer/results"
+	"hash"
+	"io"
+	"io/ioutil"
+)
+
+// bodyReader measures a response body as it is read: the bytes read, and the
+// words, lines and hash of the first limit bytes, and whether there was more.
+type bodyReader struct {
+	r     io.ReadCloser
+	n     int64
+	limit int64
+	// Measurements of the first limit bytes
+	words  int64
+	lines  int64
+	inWord bool
+	last   byte
+	hash   hash.Hash
+	// Set if reading failed, such as for a redirect's closed body
+	failed bool
+	// Set if the body is longer than limit
+	truncated bool
+}
+
+func newBodyReader(r io.ReadCloser, limit int64) *bodyReader {
+	return &bodyReader{r: r, limit: limit, hash: sha256.New()}
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.measure(p[:n])
+	b.n += int64(n)
+	if b.n > b.limit {
+		b.truncated = true
+	}
+	if err != nil && err != io.EOF {
+		b.failed = true
+	}
+	return n, err
+}
+
+func (b *bodyReader) Close() error {
+	return b.r.Close()
+}
+
+func (b *bodyReader) measure(p []byte) {
+	rest := b.limit - b.n
+	if rest <= 0 || len(p) == 0 {
+		return
+	}
+	if int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	b.hash.Write(p)
+	for _, c := range p {
+		switch c {
+		case '\n':
+			b.lines++
+			b.inWord = false
+		case ' ', '\t', '\r', '\v', '\f':
+			b.inWord = false
+		default:
+			if !b.inWord {
+				b.words++
+				b.inWord = true
+			}
+		}
+	}
+	b.last = p[len(p)-1]
+}
+
+// Read whatever is left of the first limit bytes, and check if there is more.
+func (b *bodyReader) drain() {
+	if b.limit <= 0 || b.failed || b.truncated {
+		return
+	}
+	if rest := b.limit - b.n; rest > 0 {
+		if _, err := io.CopyN(ioutil.Discard, b, rest); err != nil {
+			return
+		}
+	}
+	// Not counted, so the length is of what was measured
+	if n, _ := io.ReadFull(b.r, make([]byte, 1)); n > 0 {
+		b.truncated = true
+	}
+}
+
+// Record the measurements on a result.  The length is the bytes read, or the
+// Content-Length if that is longer, and is left alone if nothing was read.
+// The result is marked truncated if the body is longer than was measured.
+func (b *bodyReader) record(r *results.Result, contentLength int64) {
+	if b.n == 0 && (b.failed || b.limit <= 0) {
+		return
+	}
+	r.Length = b.n
+	if contentLength > r.Length {
+		r.Length = contentLength
+	}
+	if b.limit <= 0 {
+		return
+	}
+	r.Words = b.words
+	r.Lines = b.lines
+	if b.n > 0 && b.last != '\n' {
+		r.Lines++
+	}
+	r.Hash = hex.EncodeToString(b.hash.Sum(nil))
+	r.Truncated = b.truncated || contentLength > b.limit
+}
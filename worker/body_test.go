@@ -0,0 +1,145 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/Matir/webborer/client/mock"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBodyReader(t *testing.T) {
+	cases := []struct {
+		body      string
+		limit     int64
+		words     int64
+		lines     int64
+		hash      string
+		truncated bool
+	}{
+		{"", 100, 0, 0, sha256Hex(""), false},
+		{"one two\nthree\n", 100, 3, 2, sha256Hex("one two\nthree\n"), false},
+		{"  one\ttwo\r\n\nthree", 100, 3, 3, sha256Hex("  one\ttwo\r\n\nthree"), false},
+		{"one two three four", 7, 2, 1, sha256Hex("one two"), true},
+		{"one two", 7, 2, 1, sha256Hex("one two"), false},
+	}
+	for _, c := range cases {
+		// Read a byte at a time to measure across reads
+		b := newBodyReader(ioutil.NopCloser(iotest.OneByteReader(strings.NewReader(c.body))), c.limit)
+		b.drain()
+		r := &results.Result{Length: -1}
+		b.record(r, -1)
+		if r.Words != c.words || r.Lines != c.lines || r.Hash != c.hash {
+			t.Errorf("%q: expected %d words, %d lines, %s, got %d, %d, %s", c.body, c.words, c.lines, c.hash, r.Words, r.Lines, r.Hash)
+		}
+		if r.Truncated != c.truncated {
+			t.Errorf("%q: expected truncated %v, got %v", c.body, c.truncated, r.Truncated)
+		}
+		if expected := int64(len(c.body)); expected > c.limit {
+			if r.Length != c.limit {
+				t.Errorf("%q: expected to read %d bytes, got %d", c.body, c.limit, r.Length)
+			}
+		} else if r.Length != expected {
+			t.Errorf("%q: expected length %d, got %d", c.body, expected, r.Length)
+		}
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(_ []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestBodyReader_Unread(t *testing.T) {
+	// A redirect's body is already closed
+	b := newBodyReader(ioutil.NopCloser(errReader{errors.New("read on closed body")}), 100)
+	b.drain()
+	r := &results.Result{Length: 42}
+	b.record(r, 42)
+	if r.Length != 42 || r.Hash != "" {
+		t.Errorf("Expected Content-Length and no hash, got %d, %q", r.Length, r.Hash)
+	}
+	// Not reading bodies
+	b = newBodyReader(ioutil.NopCloser(strings.NewReader("abc")), 0)
+	b.drain()
+	r = &results.Result{Length: -1}
+	b.record(r, -1)
+	if r.Length != -1 || r.Hash != "" {
+		t.Errorf("Expected body not to be measured, got %d, %q", r.Length, r.Hash)
+	}
+}
+
+func TestBodyReader_ContentLength(t *testing.T) {
+	// Already read in full, by a page worker
+	b := newBodyReader(ioutil.NopCloser(strings.NewReader("one two three")), 3)
+	ioutil.ReadAll(b)
+	b.drain()
+	r := &results.Result{Length: -1}
+	b.record(r, -1)
+	if !r.Truncated || r.Length != 13 || r.Hash != sha256Hex("one") {
+		t.Errorf("Expected truncated body of 13 bytes, got %v, %d, %s", r.Truncated, r.Length, r.Hash)
+	}
+	// Body cut short of its Content-Length
+	b = newBodyReader(ioutil.NopCloser(strings.NewReader("one")), 3)
+	b.drain()
+	r = &results.Result{Length: -1}
+	b.record(r, 10)
+	if !r.Truncated || r.Length != 10 {
+		t.Errorf("Expected truncated body of 10 bytes, got %v, %d", r.Truncated, r.Length)
+	}
+}
+
+func TestTryTask_BodyMetrics(t *testing.T) {
+	body := "<html>\n<p>Hello world</p>\n</html>"
+	resp := mock.ResponseFromString(body)
+	resp.StatusCode = 200
+	resp.ContentLength = -1
+	rchan := make(chan *results.Result, 1)
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: resp},
+		settings: &settings.ScanSettings{MaxBodySize: 1024},
+		rchan:    rchan,
+		adder:    noopUrl,
+	}
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/x"}))
+	r := <-rchan
+	if r.Length != int64(len(body)) || r.Words != 4 || r.Lines != 3 || r.Hash != sha256Hex(body) || r.Truncated {
+		t.Errorf("Unexpected body metrics: %d bytes, %d words, %d lines, %s", r.Length, r.Words, r.Lines, r.Hash)
+	}
+	if r.Duration <= 0 {
+		t.Errorf("Expected a duration, got %s", r.Duration)
+	}
+	if _, err := resp.Body.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected body to be read to the end, got %v", err)
+	}
+}
@@ -64,6 +64,8 @@ func (w *HTMLWorker) Handle(t *task.Task, body io.Reader, result *results.Result
 	for _, u := range foundURLs {
 		t := t.Copy()
 		t.URL = u
+		t.Depth++
+		t.Source = task.SourceHTML
 		newTasks = append(newTasks, t)
 	}
 	w.adder(newTasks...)
@@ -94,6 +94,11 @@ func TestHandle(t *testing.T) {
 	if !compareURLSlice(expected, uResults) {
 		t.Fatalf("Results do not match.  Expected: %v, got %v.", expected, resultlist)
 	}
+	for _, v := range resultlist {
+		if v.Depth != madeTask.Depth+1 {
+			t.Errorf("Expected depth %d for %s, got %d.", madeTask.Depth+1, v.URL, v.Depth)
+		}
+	}
 }
 
 func TestEligible(t *testing.T) {
@@ -0,0 +1,333 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/Matir/webborer/client"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/stats"
+	"github.com/Matir/webborer/task"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Requests for random names made to calibrate each directory
+	soft404Probes = 3
+	// Most of a body read to fingerprint it
+	maxFingerprintBody = 256 * 1024
+	// Hashes kept to estimate the similarity of two bodies
+	sketchSize = 128
+	// Words per shingle when comparing bodies
+	shingleWords = 3
+	// Similarity above which two bodies are considered the same page
+	similarThreshold = 0.8
+	// Directories calibrated at once ahead of their expansion
+	soft404Calibrators = 4
+)
+
+// Soft404Detector recognises servers answering requests for paths that don't
+// exist with something other than a 404.  Each directory is calibrated by
+// requesting random names in it, once for each kind of name (no extension, each
+// extension and subdirectories), and responses with bodies like those are soft
+// 404s.  Directories are calibrated by a Soft404Calibrator before they are
+// expanded, and any other kind of name when it is first checked.  It is shared
+// by all of the workers in a scan.
+type Soft404Detector struct {
+	calibrations map[string]*calibration
+	sync.Mutex
+}
+
+type calibration struct {
+	// Closed once the fingerprints are known
+	ready  chan struct{}
+	prints []*fingerprint
+}
+
+// Fingerprint of a response, with the requested name removed so that pages
+// which echo the path can be compared.
+type fingerprint struct {
+	code     int
+	location string
+	length   int
+	words    int
+	sketch   []uint64
+}
+
+// Create a Soft404Detector with no directories calibrated.
+func NewSoft404Detector() *Soft404Detector {
+	return &Soft404Detector{
+		calibrations: make(map[string]*calibration),
+	}
+}
+
+// Get the calibration for key, starting one if there is none.  Returns
+// whether it was started, in which case the caller must finish it.
+func (d *Soft404Detector) start(key string) (*calibration, bool) {
+	d.Lock()
+	defer d.Unlock()
+	c, ok := d.calibrations[key]
+	if !ok {
+		c = &calibration{ready: make(chan struct{})}
+		d.calibrations[key] = c
+	}
+	return c, !ok
+}
+
+// Finish a calibration with the fingerprints of responses for random names,
+// or nil if it failed.  A failed calibration is forgotten so the next caller
+// tries again.
+func (d *Soft404Detector) finish(key string, c *calibration, prints []*fingerprint) {
+	c.prints = prints
+	if prints == nil {
+		d.Lock()
+		delete(d.calibrations, key)
+		d.Unlock()
+	}
+	close(c.ready)
+}
+
+// Check if a response fingerprint matches the calibration for key.  If there
+// is none, calibrate is run to get the fingerprints of responses for random
+// names, or nil if it failed, and other callers wait for it.  Returns false if
+// ctx is done first.
+func (d *Soft404Detector) check(ctx context.Context, key string, fp *fingerprint, calibrate func() []*fingerprint) bool {
+	c, started := d.start(key)
+	if started {
+		d.finish(key, c, calibrate())
+	} else {
+		select {
+		case <-c.ready:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for _, p := range c.prints {
+		if p.matches(fp) {
+			return true
+		}
+	}
+	return false
+}
+
+// Soft404Calibrator calibrates each directory before the wordlist is expanded
+// in it, so that the first responses for its names can be compared.
+type Soft404Calibrator struct {
+	ctx      context.Context
+	detector *Soft404Detector
+	// Suffixes of the names expanded in each directory
+	suffixes []string
+	// Idle workers to make the requests with
+	workers chan *Worker
+}
+
+// Create a Soft404Calibrator for the names that will be requested with
+// settings, recording its requests in st, which may be nil.
+func NewSoft404Calibrator(ctx context.Context, settings *ss.ScanSettings, factory client.ClientFactory, detector *Soft404Detector, st *stats.Stats) *Soft404Calibrator {
+	c := &Soft404Calibrator{
+		ctx:      ctx,
+		detector: detector,
+		suffixes: []string{""},
+		workers:  make(chan *Worker, soft404Calibrators),
+	}
+	if settings.AddSlashes {
+		c.suffixes = append(c.suffixes, "/")
+	}
+	for _, ext := range settings.Extensions {
+		c.suffixes = append(c.suffixes, "."+ext)
+	}
+	for i := 0; i < soft404Calibrators; i++ {
+		w := NewWorker(settings, factory, nil, nil, nil, nil)
+		w.SetContext(ctx)
+		w.SetStats(st)
+		c.workers <- w
+	}
+	return c
+}
+
+// Calibrate the directory that t will be expanded in.  The calibrations are
+// registered before returning, so that checks for the names in it wait for
+// them, and made in the background.  Blocks while all of the calibrators are
+// busy.
+func (c *Soft404Calibrator) Calibrate(t *task.Task) {
+	var w *Worker
+	select {
+	case w = <-c.workers:
+	case <-c.ctx.Done():
+		return
+	}
+	t = t.Copy()
+	dir := t.URL.Path
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	type pending struct {
+		key, dir, suffix string
+		cal              *calibration
+	}
+	var todo []pending
+	for _, suffix := range c.suffixes {
+		probe := t.Copy()
+		probe.URL.Path = dir + "_" + suffix
+		key, pdir, psuffix := soft404Key(probe)
+		if cal, started := c.detector.start(key); started {
+			todo = append(todo, pending{key, pdir, psuffix, cal})
+		}
+	}
+	go func() {
+		defer func() {
+			c.workers <- w
+		}()
+		for _, p := range todo {
+			c.detector.finish(p.key, p.cal, w.calibrate(c.ctx, t, p.dir, p.suffix))
+		}
+	}()
+}
+
+// Get the calibration key for a task, and the directory and suffix of the
+// random names to calibrate it with.  Names are grouped by directory and
+// extension, with subdirectories apart.
+func soft404Key(t *task.Task) (key, dir, suffix string) {
+	urlPath := t.URL.Path
+	dir, name := path.Split(urlPath)
+	suffix = path.Ext(name)
+	if strings.HasSuffix(urlPath, "/") && urlPath != "/" {
+		dir, _ = path.Split(strings.TrimSuffix(urlPath, "/"))
+		suffix = "/"
+	}
+	key = t.URL.Scheme + "://" + t.URL.Host + dir + "*" + suffix
+	if t.Host != "" {
+		key += " (" + t.Host + ")"
+	}
+	return key, dir, suffix
+}
+
+func randomName() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Build a fingerprint for a response to a request for urlPath.
+func newFingerprint(urlPath string, code int, location string, body []byte) *fingerprint {
+	name := path.Base(strings.TrimSuffix(urlPath, "/"))
+	strip := func(s string) string {
+		s = strings.Replace(s, urlPath, "", -1)
+		if len(name) >= 3 {
+			s = strings.Replace(s, name, "", -1)
+		}
+		return s
+	}
+	text := strip(string(body))
+	words := strings.Fields(text)
+	return &fingerprint{
+		code:     code,
+		location: strip(location),
+		length:   len(text),
+		words:    len(words),
+		sketch:   sketch(words),
+	}
+}
+
+// Keep the smallest hashes of the body's shingles, a bottom-k sketch.
+func sketch(words []string) []uint64 {
+	seen := make(map[uint64]bool)
+	for i := 0; i == 0 || i+shingleWords <= len(words); i++ {
+		end := i + shingleWords
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		for _, w := range words[i:end] {
+			h.Write([]byte(w))
+			h.Write([]byte{0})
+		}
+		seen[h.Sum64()] = true
+	}
+	hashes := make([]uint64, 0, len(seen))
+	for h := range seen {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	if len(hashes) > sketchSize {
+		hashes = hashes[:sketchSize]
+	}
+	return hashes
+}
+
+// Estimate the Jaccard similarity of two sets of shingles from their sketches.
+func similarity(a, b []uint64) float64 {
+	inA := make(map[uint64]bool, len(a))
+	for _, h := range a {
+		inA[h] = true
+	}
+	inB := make(map[uint64]bool, len(b))
+	for _, h := range b {
+		inB[h] = true
+	}
+	union := make([]uint64, 0, len(a)+len(b))
+	union = append(union, a...)
+	for _, h := range b {
+		if !inA[h] {
+			union = append(union, h)
+		}
+	}
+	sort.Slice(union, func(i, j int) bool { return union[i] < union[j] })
+	if len(union) > sketchSize {
+		union = union[:sketchSize]
+	}
+	if len(union) == 0 {
+		return 1
+	}
+	both := 0
+	for _, h := range union {
+		if inA[h] && inB[h] {
+			both++
+		}
+	}
+	return float64(both) / float64(len(union))
+}
+
+// Check if two responses look like the same page.  They must have the same
+// status and redirect, and similar bodies.
+func (f *fingerprint) matches(o *fingerprint) bool {
+	if f.code != o.code || f.location != o.location {
+		return false
+	}
+	return similarity(f.sketch, o.sketch) >= similarThreshold
+}
+
+// Read the start of a body for fingerprinting, returning it and a body that
+// still reads in full.
+func peekBody(body io.ReadCloser) ([]byte, io.ReadCloser) {
+	data, _ := ioutil.ReadAll(io.LimitReader(body, maxFingerprintBody))
+	return data, &peekedBody{io.MultiReader(bytes.NewReader(data), body), body}
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
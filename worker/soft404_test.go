@@ -0,0 +1,264 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"github.com/Matir/webborer/client"
+	"github.com/Matir/webborer/results"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const notFoundPage = `<html><head><title>Oops</title></head><body>
+<h1>Sorry!</h1><p>We looked everywhere, but the page %s could not be found.
+Try the <a href="/">home page</a> or our search instead.</p></body></html>`
+
+func TestSoft404Key(t *testing.T) {
+	cases := []struct {
+		path, host string
+		key        string
+		dir        string
+		suffix     string
+	}{
+		{"/", "", "http://localhost/*", "/", ""},
+		{"/admin", "", "http://localhost/*", "/", ""},
+		{"/a/b/index.php", "", "http://localhost/a/b/*.php", "/a/b/", ".php"},
+		{"/a/b/", "", "http://localhost/a/*/", "/a/", "/"},
+		{"/a", "vhost", "http://localhost/*", "/", ""},
+	}
+	for _, c := range cases {
+		tk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: c.path})
+		tk.Host = c.host
+		key, dir, suffix := soft404Key(tk)
+		if c.host != "" {
+			c.key += " (" + c.host + ")"
+		}
+		if key != c.key || dir != c.dir || suffix != c.suffix {
+			t.Errorf("soft404Key(%s): expected %q, %q, %q, got %q, %q, %q", c.path, c.key, c.dir, c.suffix, key, dir, suffix)
+		}
+	}
+}
+
+func TestFingerprint_Matches(t *testing.T) {
+	page := func(p string) []byte {
+		return []byte(fmt.Sprintf(notFoundPage, p))
+	}
+	probe := newFingerprint("/0123456789abcdef", 200, "", page("/0123456789abcdef"))
+	// Reflected names are ignored
+	if !probe.matches(newFingerprint("/admin", 200, "", page("/admin"))) {
+		t.Error("Expected page echoing the path to match.")
+	}
+	if probe.matches(newFingerprint("/admin", 404, "", page("/admin"))) {
+		t.Error("Expected different status not to match.")
+	}
+	long := strings.Repeat("Welcome to the admin console. ", 20)
+	if probe.matches(newFingerprint("/admin", 200, "", []byte(long))) {
+		t.Error("Expected a different page not to match.")
+	}
+	small := newFingerprint("/0123456789abcdef", 200, "", []byte("<p>Sorry, /0123456789abcdef was not found.</p>"))
+	if small.matches(newFingerprint("/admin", 200, "", []byte("<p>Welcome back, you are logged in.</p>"))) {
+		t.Error("Expected a different page of about the same size not to match.")
+	}
+	same := newFingerprint("/0123456789abcdef", 200, "", []byte("Login required today"))
+	if same.matches(newFingerprint("/admin", 200, "", []byte("Access granted today"))) {
+		t.Error("Expected a different page of the same size not to match.")
+	}
+	// Similar bodies of a different size still match
+	nav := ""
+	for i := 0; i < 10; i++ {
+		nav += fmt.Sprintf("<li><a href=\"/section/%d\">Section %d of the site</a></li>\n", i, i)
+	}
+	probe = newFingerprint("/0123456789abcdef", 200, "", []byte(nav+string(page("/0123456789abcdef"))))
+	longer := nav + string(page("/admin")) + "\n<!-- Served by node 12 in 35ms -->"
+	if !probe.matches(newFingerprint("/admin", 200, "", []byte(longer))) {
+		t.Error("Expected similar body to match.")
+	}
+	redir := newFingerprint("/0123456789abcdef", 302, "/login?next=/0123456789abcdef", nil)
+	if !redir.matches(newFingerprint("/backup", 302, "/login?next=/backup", nil)) {
+		t.Error("Expected redirects to the same place to match.")
+	}
+	if redir.matches(newFingerprint("/backup", 302, "/backup/", nil)) {
+		t.Error("Expected redirects elsewhere not to match.")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	words := strings.Fields(strings.Repeat("lorem ipsum dolor sit amet consectetur ", 50))
+	if s := similarity(sketch(words), sketch(words)); s != 1 {
+		t.Errorf("Expected identical bodies to have similarity 1, got %f", s)
+	}
+	other := strings.Fields("entirely different text with nothing in common")
+	if s := similarity(sketch(words), sketch(other)); s != 0 {
+		t.Errorf("Expected different bodies to have similarity 0, got %f", s)
+	}
+}
+
+func TestSoft404Detector_RetryFailed(t *testing.T) {
+	d := NewSoft404Detector()
+	fp := newFingerprint("/admin", 200, "", []byte("Not here"))
+	calls := 0
+	failing := func() []*fingerprint {
+		calls++
+		return nil
+	}
+	if d.check(context.Background(), "key", fp, failing) {
+		t.Error("Expected no match when calibration fails.")
+	}
+	working := func() []*fingerprint {
+		calls++
+		return []*fingerprint{newFingerprint("/0123456789abcdef", 200, "", []byte("Not here"))}
+	}
+	if !d.check(context.Background(), "key", fp, working) {
+		t.Error("Expected failed calibration to be retried.")
+	}
+	d.check(context.Background(), "key", fp, working)
+	if calls != 2 {
+		t.Errorf("Expected 2 calibrations, got %d", calls)
+	}
+}
+
+func TestTryTask_Soft404(t *testing.T) {
+	var mu sync.Mutex
+	probes := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/real":
+			w.Write([]byte(strings.Repeat("Real content here. ", 40)))
+		case "/fake", "/other":
+			fmt.Fprintf(w, notFoundPage, r.URL.Path)
+		default:
+			mu.Lock()
+			probes++
+			mu.Unlock()
+			fmt.Fprintf(w, notFoundPage, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	factory, err := client.NewProxyClientFactory(nil, time.Second, "test")
+	if err != nil {
+		t.Fatalf("Unable to build factory: %v", err)
+	}
+	rchan := make(chan *results.Result, 3)
+	ss := &settings.ScanSettings{Method: "GET"}
+	w := NewWorker(ss, factory, nil, noopUrl, noopDone, rchan)
+	w.SetContext(context.Background())
+	w.SetSoft404Detector(NewSoft404Detector())
+	base, _ := url.Parse(srv.URL)
+	for _, p := range []string{"/real", "/fake", "/other"} {
+		u := *base
+		u.Path = p
+		tk := task.NewTaskFromURL(&u)
+		tk.Source = task.SourceWordlist
+		w.TryTask(tk)
+	}
+	close(rchan)
+	soft := make(map[string]bool)
+	for r := range rchan {
+		soft[r.URL.Path] = r.Soft404
+		if r.Soft404 == results.ReportResult(r) {
+			t.Errorf("Expected %s to be reported only if not a soft 404.", r.URL.Path)
+		}
+	}
+	if soft["/real"] || !soft["/fake"] || !soft["/other"] {
+		t.Errorf("Unexpected soft 404s: %v", soft)
+	}
+	if probes != soft404Probes {
+		t.Errorf("Expected directory to be calibrated once with %d probes, got %d", soft404Probes, probes)
+	}
+}
+
+func TestSoft404Calibrator(t *testing.T) {
+	var mu sync.Mutex
+	probes := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dir/real.php":
+			w.Write([]byte(strings.Repeat("Real content here. ", 40)))
+		case "/dir/fake", "/dir/fake.php", "/dir/fake/":
+			fmt.Fprintf(w, notFoundPage, r.URL.Path)
+		default:
+			suffix := path.Ext(r.URL.Path)
+			if strings.HasSuffix(r.URL.Path, "/") {
+				suffix = "/"
+			}
+			mu.Lock()
+			probes[suffix]++
+			mu.Unlock()
+			fmt.Fprintf(w, notFoundPage, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	factory, err := client.NewProxyClientFactory(nil, time.Second, "test")
+	if err != nil {
+		t.Fatalf("Unable to build factory: %v", err)
+	}
+	ss := &settings.ScanSettings{
+		Method:     "GET",
+		AddSlashes: true,
+		Extensions: []string{"php"},
+	}
+	detector := NewSoft404Detector()
+	calibrator := NewSoft404Calibrator(context.Background(), ss, factory, detector, nil)
+	base, _ := url.Parse(srv.URL + "/dir")
+	calibrator.Calibrate(task.NewTaskFromURL(base))
+	detector.Lock()
+	if len(detector.calibrations) != 3 {
+		t.Errorf("Expected 3 calibrations to be started, got %d", len(detector.calibrations))
+	}
+	for key, c := range detector.calibrations {
+		detector.Unlock()
+		<-c.ready
+		if c.prints == nil {
+			t.Errorf("Expected calibration of %s to succeed.", key)
+		}
+		detector.Lock()
+	}
+	detector.Unlock()
+
+	rchan := make(chan *results.Result, 4)
+	w := NewWorker(ss, factory, nil, noopUrl, noopDone, rchan)
+	w.SetContext(context.Background())
+	w.SetSoft404Detector(detector)
+	for _, p := range []string{"/dir/real.php", "/dir/fake", "/dir/fake.php", "/dir/fake/"} {
+		u := *base
+		u.Path = p
+		tk := task.NewTaskFromURL(&u)
+		tk.Source = task.SourceWordlist
+		w.TryTask(tk)
+	}
+	close(rchan)
+	for r := range rchan {
+		if r.Soft404 != (r.URL.Path != "/dir/real.php") {
+			t.Errorf("Unexpected soft 404 result for %s: %v", r.URL.Path, r.Soft404)
+		}
+	}
+	for _, suffix := range []string{"", "/", ".php"} {
+		if probes[suffix] != soft404Probes {
+			t.Errorf("Expected %d probes for %q, got %d", soft404Probes, suffix, probes[suffix])
+		}
+	}
+}
@@ -17,17 +17,22 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"github.com/Matir/webborer/client"
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/results"
 	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/stats"
 	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/throttle"
 	"github.com/Matir/webborer/util"
 	"github.com/Matir/webborer/workqueue"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -62,8 +67,22 @@ type Worker struct {
 	stop chan bool
 	// Request for redirection
 	redir *http.Request
+	// Start of the last request made
+	started time.Time
 	// Channel to signal worker stopping
 	waitq chan bool
+	// Once done, remaining tasks are skipped
+	ctx context.Context
+	// Number of tasks skipped
+	skipped int64
+	// Slots for requests in progress per host, may be nil
+	limiter *throttle.HostLimiter
+	// Shared limit on retries, may be nil for unlimited
+	retryBudget *client.RequestBudget
+	// Shared soft 404 calibrations, may be nil to report all responses
+	soft404 *Soft404Detector
+	// Request statistics, may be nil
+	stats *stats.Stats
 }
 
 // Construct a worker with given settings.
@@ -98,6 +117,51 @@ func (w *Worker) SetPageWorker(pw PageWorker) {
 	w.pageWorker = pw
 }
 
+// Set a context for the worker.  Once the context is done, tasks are marked
+// done without being requested so that the pipeline drains.
+func (w *Worker) SetContext(ctx context.Context) {
+	w.ctx = ctx
+}
+
+// Release a slot in limiter after handling each task, which a HostGate took
+// when handing the task out.
+func (w *Worker) SetHostLimiter(limiter *throttle.HostLimiter) {
+	w.limiter = limiter
+}
+
+// Limit the retries made by all workers sharing the budget.
+func (w *Worker) SetRetryBudget(budget *client.RequestBudget) {
+	w.retryBudget = budget
+}
+
+// Suppress responses that match the server's response for nonexistent paths.
+func (w *Worker) SetSoft404Detector(d *Soft404Detector) {
+	w.soft404 = d
+}
+
+// Record request statistics.
+func (w *Worker) SetStats(st *stats.Stats) {
+	w.stats = st
+}
+
+// Number of tasks skipped because the scan was stopped.
+func (w *Worker) Skipped() int64 {
+	return atomic.LoadInt64(&w.skipped)
+}
+
+// Check if the worker's context is done.
+func (w *Worker) cancelled() bool {
+	if w.ctx == nil {
+		return false
+	}
+	select {
+	case <-w.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 // Run the worker, processing input from a channel until either signalled to
 // stop or the input channel is closed.
 func (w *Worker) Run() {
@@ -130,6 +194,13 @@ func (w *Worker) Wait() {
 }
 
 func (w *Worker) HandleTask(t *task.Task) {
+	defer w.limiter.Release(t.URL.Host)
+	if w.cancelled() {
+		logging.Logf(logging.LogDebug, "Skipping %s, scan cancelled.", t.String())
+		atomic.AddInt64(&w.skipped, 1)
+		w.done(t)
+		return
+	}
 	logging.Logf(logging.LogDebug, "Trying Raw URL (unmangled): %s", t.String())
 	code := w.TryTask(t)
 	if !util.URLIsDir(t.URL) {
@@ -138,7 +209,7 @@ func (w *Worker) HandleTask(t *task.Task) {
 		}
 	}
 	// Mark as done
-	w.done(1)
+	w.done(t)
 }
 
 func (w *Worker) TryMangleTask(t *task.Task) {
@@ -153,6 +224,9 @@ func (w *Worker) TryMangleTask(t *task.Task) {
 	dirname := clone.URL.Path[:spos]
 	basename := clone.URL.Path[spos+1:]
 	for _, newname := range Mangle(basename) {
+		if w.cancelled() {
+			return
+		}
 		clone := clone.Copy()
 		clone.URL.Path = dirname + "/" + newname
 		w.TryTask(clone)
@@ -162,30 +236,146 @@ func (w *Worker) TryMangleTask(t *task.Task) {
 func (w *Worker) TryTask(t *task.Task) int {
 	logging.Logf(logging.LogInfo, "Trying: %s", t.String())
 	w.redir = nil
-	defer w.Sleep()
-	method := w.settings.Method
-	if resp, err := w.client.Request(t.URL, t.Host, method, t.Header); err != nil && w.redir == nil {
+	ctx := w.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if resp, retries, err := w.request(ctx, t); err == client.ErrBudgetExhausted {
+		logging.Logf(logging.LogDebug, "Skipping %s, request budget exhausted.", t.String())
+		atomic.AddInt64(&w.skipped, 1)
+		return 0
+	} else if err == client.ErrRateLimiterStopped || (err != nil && ctx.Err() != nil) {
+		logging.Logf(logging.LogDebug, "Skipping %s, scan stopped.", t.String())
+		atomic.AddInt64(&w.skipped, 1)
+		return 0
+	} else if err != nil && w.redir == nil {
+		latency := time.Since(w.started)
 		result := w.ResultForError(t, resp, err)
+		result.Retries = retries
+		result.Duration = latency
+		if retries > 0 {
+			logging.Logf(logging.LogInfo, "Request for %s failed after %d retries: %s", t.String(), retries, err)
+		}
 		w.rchan <- result
 		if resp == nil {
+			w.stats.Record(0, 0, latency, err)
 			return 0
 		}
+		w.stats.Record(resp.StatusCode, resp.ContentLength, latency, err)
 		return resp.StatusCode
 	} else {
-		defer resp.Body.Close()
+		latency := time.Since(w.started)
+		body := newBodyReader(resp.Body, w.settings.MaxBodySize)
+		resp.Body = body
+		defer func() {
+			resp.Body.Close()
+			size := body.n
+			if resp.ContentLength > size {
+				size = resp.ContentLength
+			}
+			w.stats.Record(resp.StatusCode, size, latency, nil)
+		}()
+		soft404 := w.isSoft404(ctx, t, resp)
 		// Do we keep going?
-		if util.URLIsDir(t.URL) && w.KeepSpidering(resp.StatusCode) {
-			logging.Logf(logging.LogDebug, "Referring %s back for spidering.", t.String())
-			w.adder(t)
+		if !soft404 {
+			if util.URLIsDir(t.URL) && w.KeepSpidering(resp.StatusCode) {
+				logging.Logf(logging.LogDebug, "Referring %s back for spidering.", t.String())
+				// The queue gives it a new Origin, so t is left alone
+				w.adder(t.Copy())
+			}
+			w.spiderRedirect(t)
 		}
-		w.spiderRedirect(t)
 		result := w.ResultForResponse(t, resp)
+		result.Retries = retries
+		result.Soft404 = soft404
 		w.runPageWorkers(t, resp, result)
+		body.drain()
+		body.record(result, resp.ContentLength)
+		result.Duration = time.Since(w.started)
 		w.rchan <- result
+		if soft404 {
+			return 0
+		}
 		return resp.StatusCode
 	}
 }
 
+// Make the request for a task, retrying transient errors.  Returns the number
+// of retries made.
+func (w *Worker) request(ctx context.Context, t *task.Task) (*http.Response, int, error) {
+	for attempt := 0; ; attempt++ {
+		w.redir = nil
+		w.started = time.Now()
+		resp, err := w.client.Request(t.URL, t.Host, w.settings.Method, t.Header)
+		if err == nil || w.redir != nil || ctx.Err() != nil || attempt >= w.settings.Retries || !client.IsTransient(err) {
+			return resp, attempt, err
+		}
+		if w.retryBudget != nil && !w.retryBudget.Take() {
+			return resp, attempt, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		delay := client.RetryDelay(w.settings.RetryBackoff, attempt)
+		logging.Logf(logging.LogInfo, "Retrying %s in %s: %s", t.String(), delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, err
+		}
+	}
+}
+
+// Check if a response that would be reported matches the responses for
+// random names in the same directory.  Seeds are always reported.
+func (w *Worker) isSoft404(ctx context.Context, t *task.Task, resp *http.Response) bool {
+	if w.soft404 == nil || t.Source == task.SourceSeed || !results.FoundSomething(resp.StatusCode) {
+		return false
+	}
+	body, rest := peekBody(resp.Body)
+	resp.Body = rest
+	fp := newFingerprint(t.URL.Path, resp.StatusCode, resp.Header.Get("Location"), body)
+	key, dir, suffix := soft404Key(t)
+	calibrate := func() []*fingerprint {
+		return w.calibrate(ctx, t, dir, suffix)
+	}
+	if !w.soft404.check(ctx, key, fp, calibrate) {
+		return false
+	}
+	logging.Logf(logging.LogDebug, "Suppressing %s, matches the response for nonexistent paths.", t.String())
+	return true
+}
+
+// Fingerprint the responses for random names in dir ending in suffix.
+func (w *Worker) calibrate(ctx context.Context, t *task.Task, dir, suffix string) []*fingerprint {
+	redir := w.redir
+	defer func() {
+		w.redir = redir
+	}()
+	prints := make([]*fingerprint, 0, soft404Probes)
+	for i := 0; i < soft404Probes; i++ {
+		probe := t.Copy()
+		probe.Header = t.Header
+		probe.URL.Path = dir + randomName() + suffix
+		probe.URL.RawPath = ""
+		probe.URL.RawQuery = ""
+		logging.Logf(logging.LogDebug, "Calibrating with %s", probe.String())
+		start := time.Now()
+		resp, _, err := w.request(ctx, probe)
+		if err != nil && w.redir == nil {
+			logging.Logf(logging.LogInfo, "Unable to calibrate %s for soft 404s: %s", probe.String(), err)
+			return nil
+		}
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxFingerprintBody))
+		resp.Body.Close()
+		w.stats.Record(resp.StatusCode, int64(len(body)), time.Since(start), nil)
+		prints = append(prints, newFingerprint(probe.URL.Path, resp.StatusCode, resp.Header.Get("Location"), body))
+	}
+	return prints
+}
+
 func (w *Worker) spiderRedirect(t *task.Task) {
 	if w.redir == nil {
 		return
@@ -193,6 +383,7 @@ func (w *Worker) spiderRedirect(t *task.Task) {
 	logging.Logf(logging.LogDebug, "Referring redirect %s back.", w.redir.URL.String())
 	t = t.Copy()
 	t.URL = w.redir.URL
+	t.Source = task.SourceRedirect
 	w.adder(t)
 }
 
@@ -219,12 +410,6 @@ func (w *Worker) ResultForResponse(t *task.Task, resp *http.Response) *results.R
 	return rv
 }
 
-func (w *Worker) Sleep() {
-	if w.settings.SleepTime != 0 {
-		time.Sleep(w.settings.SleepTime)
-	}
-}
-
 func (w *Worker) runPageWorkers(t *task.Task, resp *http.Response, result *results.Result) {
 	if w.pageWorker != nil && w.pageWorker.Eligible(resp) {
 		logging.Logf(logging.LogDebug, "Running page workers for task %s", t.String())
@@ -245,21 +430,38 @@ func (w *Worker) KeepSpidering(code int) bool {
 	return false
 }
 
-// Starts a batch of workers based on the relevant settings.
-func StartWorkers(settings *ss.ScanSettings,
+// Starts a batch of workers based on the relevant settings.  Soft 404s are
+// suppressed with soft404, which may be nil.
+func StartWorkers(ctx context.Context,
+	settings *ss.ScanSettings,
 	factory client.ClientFactory,
 	src <-chan *task.Task,
 	adder workqueue.QueueAddFunc,
 	done workqueue.QueueDoneFunc,
-	rchan chan<- *results.Result) []*Worker {
+	rchan chan<- *results.Result,
+	soft404 *Soft404Detector,
+	st *stats.Stats) []*Worker {
 	count := settings.Workers
 	workers := make([]*Worker, count)
+	limiter := throttle.NewHostLimiter(settings.MaxHostConns)
+	if limiter != nil {
+		src = workqueue.NewHostGate(limiter, settings.QueueSize).Run(src)
+	}
+	retryBudget := client.NewRequestBudget(settings.RetryBudget)
+	retryBudget.OnExhausted(func() {
+		logging.Logf(logging.LogWarning, "Retry budget of %d exhausted, not retrying further errors.", settings.RetryBudget)
+	})
 	for i := 0; i < count; i++ {
 		workers[i] = NewWorker(settings, factory, src, adder, done, rchan)
-		workers[i].RunInBackground()
+		workers[i].SetContext(ctx)
+		workers[i].SetHostLimiter(limiter)
+		workers[i].SetRetryBudget(retryBudget)
+		workers[i].SetSoft404Detector(soft404)
+		workers[i].SetStats(st)
 		if (settings.ParseHTML && settings.RunMode == ss.RunModeEnumeration) || settings.RunMode == ss.RunModeLinkCheck {
 			workers[i].SetPageWorker(NewHTMLWorker(adder))
 		}
+		workers[i].RunInBackground()
 	}
 	return workers
 }
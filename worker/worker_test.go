@@ -15,25 +15,30 @@
 package worker
 
 import (
+	"context"
+	"github.com/Matir/webborer/client"
 	"github.com/Matir/webborer/client/mock"
 	"github.com/Matir/webborer/results"
 	"github.com/Matir/webborer/settings"
 	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/throttle"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
-func noopInt(_ int)           {}
+func noopDone(_ *task.Task)   {}
 func noopUrl(_ ...*task.Task) {}
 
 func TestNewWorker(t *testing.T) {
 	ss := &settings.ScanSettings{}
 	src := make(chan *task.Task)
 	rchan := make(chan *results.Result)
-	worker := NewWorker(ss, &mock.MockClientFactory{}, src, noopUrl, noopInt, rchan)
+	worker := NewWorker(ss, &mock.MockClientFactory{}, src, noopUrl, noopDone, rchan)
 	if worker == nil {
 		t.Fatal("Expected to receive a worker, got nil!")
 	}
@@ -126,7 +131,7 @@ func TestTryHandleURL_Basic(t *testing.T) {
 		settings: ss,
 		rchan:    rchan,
 		adder:    noopUrl,
-		done:     noopInt,
+		done:     noopDone,
 	}
 	u := &url.URL{Scheme: "http", Host: "localhost", Path: "/index"}
 	w.HandleTask(task.NewTaskFromURL(u))
@@ -142,12 +147,15 @@ func TestStartWorkers_SingleIteration(t *testing.T) {
 	rchan := make(chan *results.Result)
 	u, _ := url.Parse("http://www.example.com")
 	for i, w := range StartWorkers(
+		context.Background(),
 		ss,
 		&mock.MockClientFactory{},
 		schan,
 		noopUrl,
-		noopInt,
-		rchan) {
+		noopDone,
+		rchan,
+		nil,
+		nil) {
 		// Send the input
 		schan <- task.NewTaskFromURL(u)
 		// Read the result
@@ -187,3 +195,97 @@ func TestSetPageWorker(t *testing.T) {
 		t.Fatalf("Pageworker not properly set.")
 	}
 }
+
+func TestHandleTask_Cancelled(t *testing.T) {
+	client := &mock.MockClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doneCount := 0
+	w := &Worker{
+		client:   client,
+		settings: &settings.ScanSettings{},
+		done:     func(_ *task.Task) { doneCount++ },
+	}
+	w.SetContext(ctx)
+	w.HandleTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}))
+	if len(client.Requests) != 0 {
+		t.Errorf("Expected no requests after cancellation, got %d", len(client.Requests))
+	}
+	if doneCount != 1 {
+		t.Errorf("Expected task to be marked done, got %d", doneCount)
+	}
+}
+
+func TestHandleTask_ReleasesHostSlot(t *testing.T) {
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = 404
+	limiter := throttle.NewHostLimiter(1)
+	// Taken by the HostGate that handed out the task
+	if !limiter.TryAcquire("localhost") {
+		t.Fatal("TryAcquire failed.")
+	}
+	w := &Worker{
+		client:   &mock.MockClient{NextResponse: resp},
+		settings: &settings.ScanSettings{},
+		rchan:    make(chan *results.Result, 1),
+		done:     noopDone,
+	}
+	w.SetHostLimiter(limiter)
+	w.HandleTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}))
+	if limiter.Active("localhost") != 0 {
+		t.Errorf("Expected slot to be released, got %d active", limiter.Active("localhost"))
+	}
+}
+
+// Run a task against a client returning timeouts, returning the results.
+func tryTaskWithErrors(w *Worker, errs int) []*results.Result {
+	resp := mock.ResponseFromString("")
+	resp.StatusCode = 200
+	mc := &mock.MockClient{NextResponse: resp}
+	for i := 0; i < errs; i++ {
+		mc.Errors = append(mc.Errors, &net.DNSError{Err: "timeout", IsTimeout: true})
+	}
+	rchan := make(chan *results.Result, 1)
+	w.client = mc
+	w.rchan = rchan
+	w.adder = noopUrl
+	w.TryTask(task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/"}))
+	close(rchan)
+	var res []*results.Result
+	for r := range rchan {
+		res = append(res, r)
+	}
+	return res
+}
+
+func TestTryTask_Retry(t *testing.T) {
+	w := &Worker{settings: &settings.ScanSettings{Retries: 2, RetryBackoff: time.Millisecond}}
+	res := tryTaskWithErrors(w, 2)
+	if len(res) != 1 || res[0].Error != nil || res[0].Code != 200 {
+		t.Fatalf("Expected success after retrying, got %v", res)
+	}
+	if res[0].Retries != 2 {
+		t.Errorf("Expected 2 retries, got %d", res[0].Retries)
+	}
+
+	res = tryTaskWithErrors(w, 3)
+	if len(res) != 1 || res[0].Error == nil {
+		t.Fatalf("Expected error after running out of retries, got %v", res)
+	}
+	if res[0].Retries != 2 {
+		t.Errorf("Expected 2 retries, got %d", res[0].Retries)
+	}
+}
+
+func TestTryTask_RetryBudget(t *testing.T) {
+	w := &Worker{settings: &settings.ScanSettings{Retries: 5}}
+	w.SetRetryBudget(client.NewRequestBudget(1))
+	res := tryTaskWithErrors(w, 3)
+	if len(res) != 1 || res[0].Error == nil || res[0].Retries != 1 {
+		t.Fatalf("Expected error after 1 retry, got %v", res)
+	}
+	res = tryTaskWithErrors(w, 1)
+	if len(res) != 1 || res[0].Error == nil || res[0].Retries != 0 {
+		t.Fatalf("Expected no retries once budget is spent, got %v", res)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/throttle"
+)
+
+// HostGate hands tasks to the workers, holding back tasks for hosts that
+// already have as many requests in progress as the HostLimiter allows.  Tasks
+// for other hosts are handed out meanwhile, so workers never wait on a busy
+// host.  A slot is taken for each task as it is handed out, and the worker
+// releases it once the task is done.
+type HostGate struct {
+	limiter *throttle.HostLimiter
+	list    *hostList
+	// Most tasks held back before reading more
+	size int
+}
+
+// Create a HostGate holding back at most size tasks.
+func NewHostGate(limiter *throttle.HostLimiter, size int) *HostGate {
+	if size < 1 {
+		size = 1
+	}
+	list := newHostList(func() taskList { return &fifoList{} })
+	list.full = limiter.Full
+	return &HostGate{
+		limiter: limiter,
+		list:    list,
+		size:    size,
+	}
+}
+
+// Pass tasks from src on to the returned channel.  It is closed once src is
+// closed and every task has been handed out.
+func (g *HostGate) Run(src <-chan *task.Task) <-chan *task.Task {
+	dst := make(chan *task.Task)
+	go func() {
+		defer close(dst)
+		var next *task.Task
+		for src != nil || next != nil || g.list.len() > 0 {
+			if next == nil {
+				// Only hosts with a free slot are chosen
+				if next = g.list.pop(); next != nil && !g.limiter.TryAcquire(next.URL.Host) {
+					g.list.push(next)
+					next = nil
+				}
+			}
+			in := src
+			if g.list.len() >= g.size {
+				in = nil
+			}
+			var out chan<- *task.Task
+			if next != nil {
+				out = dst
+			}
+			select {
+			case t, ok := <-in:
+				if !ok {
+					src = nil
+					continue
+				}
+				g.list.push(t)
+			case out <- next:
+				next = nil
+			case <-g.limiter.Released():
+			}
+		}
+	}()
+	return dst
+}
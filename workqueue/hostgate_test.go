@@ -0,0 +1,84 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/throttle"
+	"testing"
+	"time"
+)
+
+func TestHostGate(t *testing.T) {
+	limiter := throttle.NewHostLimiter(1)
+	src := make(chan *task.Task, 4)
+	for i := 0; i < 3; i++ {
+		src <- hostTask("a", i)
+	}
+	src <- hostTask("b", 0)
+	close(src)
+	dst := NewHostGate(limiter, 10).Run(src)
+
+	// A busy host doesn't hold up the others
+	if got := <-dst; got.URL.Host != "a" {
+		t.Fatalf("Expected task for a, got %s", got)
+	}
+	if got := <-dst; got.URL.Host != "b" {
+		t.Fatalf("Expected task for b while a is busy, got %s", got)
+	}
+	select {
+	case got := <-dst:
+		t.Fatalf("Expected no task while every host is busy, got %s", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+	for i := 1; i < 3; i++ {
+		limiter.Release("a")
+		if got := <-dst; got.URL.Host != "a" {
+			t.Fatalf("Expected task for a once released, got %s", got)
+		}
+	}
+	limiter.Release("a")
+	limiter.Release("b")
+	if _, ok := <-dst; ok {
+		t.Error("Expected channel to be closed.")
+	}
+}
+
+func TestHostGate_MixedCase(t *testing.T) {
+	limiter := throttle.NewHostLimiter(1)
+	src := make(chan *task.Task, 2)
+	src <- hostTask("Example.com", 0)
+	src <- hostTask("example.COM", 1)
+	close(src)
+	dst := NewHostGate(limiter, 10).Run(src)
+
+	<-dst
+	select {
+	case got := <-dst:
+		t.Fatalf("Expected the same host in another case to be held back, got %s", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+	limiter.Release("EXAMPLE.com")
+	if got := <-dst; got.URL.Path != "/1" {
+		t.Fatalf("Expected second task once released, got %s", got)
+	}
+	if limiter.Active("example.com") != 1 {
+		t.Errorf("Expected one request in progress, got %d", limiter.Active("example.com"))
+	}
+	limiter.Release("example.COM")
+	if _, ok := <-dst; ok {
+		t.Error("Expected channel to be closed.")
+	}
+}
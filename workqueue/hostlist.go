@@ -0,0 +1,132 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"github.com/Matir/webborer/task"
+	"github.com/Matir/webborer/throttle"
+	"strings"
+)
+
+// hostList keeps a separate list for each host and serves them in turn, so
+// that one large host does not starve the others.  Hosts are served by
+// smooth weighted round-robin: a host with weight 3 is served three times as
+// often as a host with weight 1, with the turns spread out.
+type hostList struct {
+	// Build the list for a new host
+	newList func() taskList
+	// Weights by host, default 1
+	weights map[string]int
+	hosts   map[string]*hostQueue
+	// Hosts in the order they were first seen
+	order []*hostQueue
+	// Total tasks in all lists
+	length int
+	// Host chosen by peek, to be served by the next pop
+	next *hostQueue
+	// Hosts that may not be served now, may be nil
+	full func(host string) bool
+}
+
+type hostQueue struct {
+	host    string
+	list    taskList
+	weight  int
+	current int
+}
+
+func newHostList(newList func() taskList) *hostList {
+	return &hostList{
+		newList: newList,
+		hosts:   make(map[string]*hostQueue),
+	}
+}
+
+// Set the weight of hosts.  Hosts may be given with or without a port.
+func (l *hostList) setWeights(weights map[string]int) {
+	l.weights = weights
+	for host, hq := range l.hosts {
+		hq.weight = l.weight(host)
+	}
+}
+
+func (l *hostList) weight(host string) int {
+	if w, ok := l.weights[host]; ok {
+		return w
+	}
+	if i := strings.LastIndexByte(host, ':'); i != -1 {
+		if w, ok := l.weights[host[:i]]; ok {
+			return w
+		}
+	}
+	return 1
+}
+
+func (l *hostList) push(t *task.Task) {
+	host := throttle.HostKey(t.URL.Host)
+	hq, ok := l.hosts[host]
+	if !ok {
+		hq = &hostQueue{host: host, list: l.newList(), weight: l.weight(host)}
+		l.hosts[host] = hq
+		l.order = append(l.order, hq)
+	}
+	hq.list.push(t)
+	l.length++
+}
+
+func (l *hostList) pop() *task.Task {
+	hq := l.choose()
+	if hq == nil {
+		return nil
+	}
+	l.next = nil
+	l.length--
+	return hq.list.pop()
+}
+
+func (l *hostList) peek() *task.Task {
+	hq := l.choose()
+	if hq == nil {
+		return nil
+	}
+	return hq.list.peek()
+}
+
+func (l *hostList) len() int {
+	return l.length
+}
+
+// Choose the host to serve next, skipping hosts that are full.  The choice is
+// kept until it is popped.  Returns nil if no host can be served.
+func (l *hostList) choose() *hostQueue {
+	if l.next != nil {
+		return l.next
+	}
+	total := 0
+	for _, hq := range l.order {
+		if hq.list.len() == 0 || (l.full != nil && l.full(hq.host)) {
+			continue
+		}
+		hq.current += hq.weight
+		total += hq.weight
+		if l.next == nil || hq.current > l.next.current {
+			l.next = hq
+		}
+	}
+	if l.next != nil {
+		l.next.current -= total
+	}
+	return l.next
+}
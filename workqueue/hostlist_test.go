@@ -0,0 +1,105 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func hostTask(host string, i int) *task.Task {
+	return task.NewTaskFromURL(&url.URL{Scheme: "http", Host: host, Path: fmt.Sprintf("/%d", i)})
+}
+
+func drainHosts(l taskList) string {
+	hosts := make([]string, 0)
+	for l.len() > 0 {
+		peeked := l.peek()
+		t := l.pop()
+		if t != peeked {
+			return "peek/pop mismatch"
+		}
+		hosts = append(hosts, t.URL.Host)
+	}
+	return strings.Join(hosts, " ")
+}
+
+func TestHostList_RoundRobin(t *testing.T) {
+	l := newHostList(func() taskList { return &fifoList{} })
+	for i := 0; i < 4; i++ {
+		l.push(hostTask("a", i))
+	}
+	l.push(hostTask("b", 0))
+	l.push(hostTask("c", 0))
+	l.push(hostTask("b", 1))
+	if l.len() != 7 {
+		t.Errorf("Expected length 7, got %d", l.len())
+	}
+	if got, want := drainHosts(l), "a b c a b a a"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if l.pop() != nil || l.peek() != nil {
+		t.Error("Expected nil from empty list.")
+	}
+}
+
+func TestHostList_Weights(t *testing.T) {
+	l := newHostList(func() taskList { return &fifoList{} })
+	l.setWeights(map[string]int{"a": 3})
+	for i := 0; i < 8; i++ {
+		l.push(hostTask("a:8080", i))
+		l.push(hostTask("b", i))
+	}
+	if got, want := drainHosts(l), "a:8080 a:8080 b a:8080 a:8080 a:8080 b a:8080 a:8080 a:8080 b b b b b b"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestHostList_PerHostOrder(t *testing.T) {
+	l := newHostList(func() taskList { return &fifoList{} })
+	for i := 0; i < 3; i++ {
+		l.push(hostTask("a", i))
+		l.push(hostTask("b", i))
+	}
+	next := map[string]int{}
+	for l.len() > 0 {
+		tk := l.pop()
+		if want := fmt.Sprintf("/%d", next[tk.URL.Host]); tk.URL.Path != want {
+			t.Errorf("Expected %s for %s, got %s", want, tk.URL.Host, tk.URL.Path)
+		}
+		next[tk.URL.Host]++
+	}
+}
+
+func TestHostList_Full(t *testing.T) {
+	l := newHostList(func() taskList { return &fifoList{} })
+	full := map[string]bool{"a": true}
+	l.full = func(host string) bool { return full[host] }
+	l.push(hostTask("a", 0))
+	l.push(hostTask("b", 0))
+	if got := l.pop(); got == nil || got.URL.Host != "b" {
+		t.Fatalf("Expected task for b, got %v", got)
+	}
+	if l.pop() != nil || l.len() != 1 {
+		t.Error("Expected full host not to be served.")
+	}
+	full["a"] = false
+	if got := l.pop(); got == nil || got.URL.Host != "a" {
+		t.Errorf("Expected task for a once not full, got %v", got)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/Matir/webborer/logging"
+	"github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// spillList keeps at most threshold tasks in an in-memory list and writes the
+// rest to a file, reading them back in batches as the list drains.  Tasks on
+// disk are kept in the order they were added, so with a priority order they
+// only compete with the tasks in memory once paged in.  The values of secret
+// headers are not written to disk, and are taken from the settings when tasks
+// are read back.
+type spillList struct {
+	mem       taskList
+	threshold int
+	// File holding spilled tasks, one JSON object per line, with separate
+	// handles for writing and reading
+	fp     *os.File
+	rfp    *os.File
+	writer *bufio.Writer
+	reader *bufio.Reader
+	// Number of tasks on disk
+	onDisk int
+	// Origins of the tasks on disk, in order, to report any that are lost
+	origins []string
+	// Header with the secret headers of tasks read back
+	header http.Header
+	// Called for each task that could not be read back, with only its Origin
+	lost func(*task.Task)
+}
+
+// Create a spillList wrapping mem, with its file in dir.  Tasks read back get
+// the secret headers in header.
+func newSpillList(mem taskList, threshold int, dir string, header http.Header, lost func(*task.Task)) (*spillList, error) {
+	// Only readable by the owner
+	fp, err := ioutil.TempFile(dir, "webborer-queue-")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create queue spill file: %s", err.Error())
+	}
+	rfp, err := os.Open(fp.Name())
+	if err != nil {
+		fp.Close()
+		os.Remove(fp.Name())
+		return nil, fmt.Errorf("Unable to open queue spill file: %s", err.Error())
+	}
+	logging.Logf(logging.LogDebug, "Spilling queue past %d tasks to %s", threshold, fp.Name())
+	return &spillList{
+		mem:       mem,
+		threshold: threshold,
+		fp:        fp,
+		rfp:       rfp,
+		writer:    bufio.NewWriter(fp),
+		reader:    bufio.NewReader(rfp),
+		header:    header,
+		lost:      lost,
+	}, nil
+}
+
+func (l *spillList) push(t *task.Task) {
+	// Once anything is on disk, new tasks go after it to keep their order
+	if l.onDisk == 0 && l.mem.len() < l.threshold {
+		l.mem.push(t)
+		return
+	}
+	if err := l.spill(t); err != nil {
+		logging.Logf(logging.LogError, "Keeping task in memory: %s", err.Error())
+		l.mem.push(t)
+	}
+}
+
+func (l *spillList) spill(t *task.Task) error {
+	saved := t.Copy()
+	saved.Header = settings.RedactHeader(saved.Header)
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("Unable to encode task %s: %s", t.String(), err.Error())
+	}
+	data = append(data, '\n')
+	if _, err := l.writer.Write(data); err != nil {
+		return fmt.Errorf("Unable to write queue spill file: %s", err.Error())
+	}
+	l.onDisk++
+	l.origins = append(l.origins, t.Origin)
+	return nil
+}
+
+func (l *spillList) pop() *task.Task {
+	l.fill()
+	return l.mem.pop()
+}
+
+func (l *spillList) peek() *task.Task {
+	l.fill()
+	return l.mem.peek()
+}
+
+func (l *spillList) len() int {
+	return l.mem.len() + l.onDisk
+}
+
+// Read tasks back from disk once the in-memory list has drained to half of
+// the threshold.
+func (l *spillList) fill() {
+	if l.onDisk == 0 || l.mem.len() > l.threshold/2 {
+		return
+	}
+	if err := l.writer.Flush(); err != nil {
+		logging.Logf(logging.LogError, "Unable to write queue spill file: %s", err.Error())
+	}
+	for l.onDisk > 0 && l.mem.len() < l.threshold {
+		line, err := l.reader.ReadBytes('\n')
+		if err != nil {
+			logging.Logf(logging.LogError, "Unable to read queue spill file, %d tasks lost: %s", l.onDisk, err.Error())
+			for _, origin := range l.origins {
+				l.lost(&task.Task{Origin: origin})
+			}
+			l.onDisk = 0
+			break
+		}
+		l.onDisk--
+		origin := l.origins[0]
+		l.origins = l.origins[1:]
+		t := &task.Task{}
+		if err := json.Unmarshal(line, t); err != nil {
+			logging.Logf(logging.LogError, "Unable to decode spilled task: %s", err.Error())
+			l.lost(&task.Task{Origin: origin})
+			continue
+		}
+		t.Header = settings.RestoreHeader(t.Header, l.header)
+		l.mem.push(t)
+	}
+	if l.onDisk == 0 {
+		l.reset()
+	}
+}
+
+// Empty the spill file so it does not keep growing.
+func (l *spillList) reset() {
+	l.origins = nil
+	if err := l.fp.Truncate(0); err != nil {
+		logging.Logf(logging.LogWarning, "Unable to truncate queue spill file: %s", err.Error())
+		return
+	}
+	for _, fp := range []*os.File{l.fp, l.rfp} {
+		if _, err := fp.Seek(0, io.SeekStart); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to rewind queue spill file: %s", err.Error())
+		}
+	}
+	l.writer.Reset(l.fp)
+	l.reader.Reset(l.rfp)
+}
+
+// Remove the spill file.
+func (l *spillList) Close() error {
+	l.fp.Close()
+	l.rfp.Close()
+	return os.Remove(l.fp.Name())
+}
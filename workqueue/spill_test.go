@@ -0,0 +1,195 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"fmt"
+	"github.com/Matir/webborer/task"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func spillDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestSpillList_Order(t *testing.T) {
+	dir := spillDir(t)
+	l, err := newSpillList(&fifoList{}, 4, dir, nil, func(lost *task.Task) {
+		t.Errorf("Lost task from %s.", lost.Origin)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := 0
+	expect := 0
+	// Interleave pushes and pops so tasks are paged in while more are spilled
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 10; i++ {
+			l.push(task.NewTaskFromURL(&url.URL{Path: fmt.Sprintf("/%d", next)}))
+			next++
+		}
+		if l.mem.len() > 4 {
+			t.Errorf("Expected at most 4 tasks in memory, got %d", l.mem.len())
+		}
+		if l.len() != next-expect {
+			t.Errorf("Expected length %d, got %d", next-expect, l.len())
+		}
+		for i := 0; i < 7; i++ {
+			tk := l.pop()
+			if want := fmt.Sprintf("/%d", expect); tk.URL.Path != want {
+				t.Fatalf("Expected %s, got %s", want, tk.URL.Path)
+			}
+			expect++
+		}
+	}
+	for l.len() > 0 {
+		tk := l.pop()
+		if want := fmt.Sprintf("/%d", expect); tk.URL.Path != want {
+			t.Fatalf("Expected %s, got %s", want, tk.URL.Path)
+		}
+		expect++
+	}
+	if expect != next {
+		t.Errorf("Expected %d tasks, got %d", next, expect)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("Error closing spill list: %s", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected spill file to be removed, found %d files", len(files))
+	}
+}
+
+func TestSpillList_KeepsFields(t *testing.T) {
+	l, err := newSpillList(newTaskList(0), 1, spillDir(t), nil, func(*task.Task) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	first := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: "/a"})
+	l.push(first)
+	second := first.Copy()
+	second.URL.Path = "/b"
+	second.Host = "example.com"
+	second.Depth = 3
+	second.Source = task.SourceHTML
+	second.Rank = 7
+	second.Origin = "http://localhost/"
+	l.push(second)
+	l.pop()
+	got := l.pop()
+	if got.String() != second.String() || got.Depth != 3 || got.Source != task.SourceHTML || got.Rank != 7 || got.Origin != second.Origin {
+		t.Errorf("Spilled task changed: %+v", got)
+	}
+}
+
+func TestSpillList_SecretHeaders(t *testing.T) {
+	header := http.Header{"Authorization": {"Bearer new"}}
+	l, err := newSpillList(newTaskList(0), 1, spillDir(t), header, func(*task.Task) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, p := range []string{"/a", "/b"} {
+		tk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: p})
+		tk.Header = http.Header{"Authorization": {"Bearer hunter2"}, "X-Team": {"red"}}
+		l.push(tk)
+	}
+	l.writer.Flush()
+	info, err := l.fp.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected spill file mode 0600, got %o", mode)
+	}
+	data, err := ioutil.ReadFile(l.fp.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 || strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected secret headers not to be spilled, got %q", data)
+	}
+	l.pop()
+	got := l.pop()
+	if got.Header.Get("Authorization") != "Bearer new" || got.Header.Get("X-Team") != "red" {
+		t.Errorf("Unexpected headers read back: %v", got.Header)
+	}
+}
+
+func TestSpillList_Lost(t *testing.T) {
+	var lost []string
+	l, err := newSpillList(newTaskList(0), 1, spillDir(t), nil, func(tk *task.Task) {
+		lost = append(lost, tk.Origin)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, p := range []string{"/a", "/b", "/c"} {
+		tk := task.NewTaskFromURL(&url.URL{Scheme: "http", Host: "localhost", Path: p})
+		tk.Origin = "http://localhost" + p
+		l.push(tk)
+	}
+	l.writer.Flush()
+	if err := l.fp.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	l.pop()
+	if got := l.pop(); got != nil {
+		t.Errorf("Expected spilled tasks to be lost, got %s", got)
+	}
+	if len(lost) != 2 || lost[0] != "http://localhost/b" || lost[1] != "http://localhost/c" {
+		t.Errorf("Expected /b and /c to be lost, got %v", lost)
+	}
+	if l.len() != 0 {
+		t.Errorf("Expected empty list, got %d", l.len())
+	}
+}
+
+func TestWorkqueue_Spill(t *testing.T) {
+	queue := NewWorkQueue(5, nil, false)
+	queue.filter = func(_ *task.Task) bool { return true }
+	if err := queue.SetSpill(3, spillDir(t), nil); err != nil {
+		t.Fatal(err)
+	}
+	queue.RunInBackground()
+	for i := 0; i < 50; i++ {
+		queue.AddTasks(task.NewTaskFromURL(&url.URL{Path: fmt.Sprintf("%d", i)}))
+	}
+	queue.InputFinished()
+	i := 0
+	for o := range queue.GetWorkChan() {
+		if s := fmt.Sprintf("%d", i); o.URL.Path != s {
+			t.Errorf("Out of order responses, got %s, expected %s", o.URL.Path, s)
+		}
+		i++
+		queue.GetDoneFunc()(o)
+	}
+	queue.WaitPipe()
+	if i != 50 {
+		t.Errorf("Expected 50 tasks, got %d", i)
+	}
+}
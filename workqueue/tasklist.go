@@ -0,0 +1,195 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	"container/heap"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+)
+
+// A taskList holds the tasks waiting in the WorkQueue, in the order they
+// should be dispatched.  Lists are unbounded.
+type taskList interface {
+	// Add a task
+	push(*task.Task)
+	// Remove and return the next task, or nil if empty
+	pop() *task.Task
+	// Return the next task without removing it, or nil if empty
+	peek() *task.Task
+	// Number of tasks in the list
+	len() int
+}
+
+// Rank of each task source for QueueOrderSource.  Tasks from sources with a
+// lower rank are dispatched first: seeds, then paths from robots.txt and
+// sitemaps, then redirects and links, and wordlist guesses last.
+var sourceRank = map[task.TaskSource]int{
+	task.SourceSeed:     0,
+	task.SourceRobots:   1,
+	task.SourceSitemap:  2,
+	task.SourceRedirect: 3,
+	task.SourceHTML:     4,
+	task.SourceWordlist: 5,
+}
+
+// Build a list for the given order.
+func newTaskList(order ss.QueueOrderOption) taskList {
+	switch order {
+	case ss.QueueOrderDepth:
+		return newPriorityList(func(a, b *task.Task) bool {
+			return a.Depth < b.Depth
+		})
+	case ss.QueueOrderRank:
+		return newPriorityList(func(a, b *task.Task) bool {
+			return a.Rank < b.Rank
+		})
+	case ss.QueueOrderSource:
+		return newPriorityList(func(a, b *task.Task) bool {
+			return sourceRank[a.Source] < sourceRank[b.Source]
+		})
+	}
+	return &fifoList{}
+}
+
+// fifoList is a singly-linked list.
+type fifoList struct {
+	// Elements to be worked on
+	head *queueNode
+	// End for cheap appends
+	tail *queueNode
+	// Number of items in list
+	length int
+}
+
+type queueNode struct {
+	// next ptr
+	next *queueNode
+	// data
+	data *task.Task
+}
+
+// Append Task to end of list
+func (l *fifoList) push(t *task.Task) {
+	node := &queueNode{data: t}
+	if l.tail != nil {
+		l.tail.next = node
+	} else {
+		l.head = node
+	}
+	l.tail = node
+	l.length++
+}
+
+// Get Task from front of list
+func (l *fifoList) pop() *task.Task {
+	node := l.head
+	if node == nil {
+		return nil
+	}
+	l.head = l.head.next
+	if l.head == nil {
+		l.tail = nil
+	}
+	l.length--
+	return node.data
+}
+
+// Get Task from front of list without removal
+func (l *fifoList) peek() *task.Task {
+	if l.head != nil {
+		return l.head.data
+	}
+	return nil
+}
+
+func (l *fifoList) len() int {
+	return l.length
+}
+
+// priorityList is a heap ordered by a comparison function.  Tasks that
+// compare equal are kept in the order they were added.
+type priorityList struct {
+	items []priorityItem
+	less  func(a, b *task.Task) bool
+	// Sequence number for the next item
+	seq uint64
+}
+
+type priorityItem struct {
+	data *task.Task
+	seq  uint64
+}
+
+func newPriorityList(less func(a, b *task.Task) bool) *priorityList {
+	return &priorityList{less: less}
+}
+
+func (l *priorityList) push(t *task.Task) {
+	heap.Push((*priorityHeap)(l), priorityItem{data: t, seq: l.seq})
+	l.seq++
+}
+
+func (l *priorityList) pop() *task.Task {
+	if len(l.items) == 0 {
+		return nil
+	}
+	return heap.Pop((*priorityHeap)(l)).(priorityItem).data
+}
+
+func (l *priorityList) peek() *task.Task {
+	if len(l.items) == 0 {
+		return nil
+	}
+	return l.items[0].data
+}
+
+func (l *priorityList) len() int {
+	return len(l.items)
+}
+
+// priorityHeap implements heap.Interface for a priorityList.
+type priorityHeap priorityList
+
+func (h *priorityHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *priorityHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.data, b.data) {
+		return true
+	}
+	if h.less(b.data, a.data) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *priorityHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *priorityHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(priorityItem))
+}
+
+func (h *priorityHeap) Pop() interface{} {
+	n := len(h.items) - 1
+	item := h.items[n]
+	h.items[n] = priorityItem{}
+	h.items = h.items[:n]
+	return item
+}
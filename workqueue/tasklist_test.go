@@ -0,0 +1,93 @@
+// Copyright 2026 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workqueue
+
+import (
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/task"
+	"net/url"
+	"testing"
+)
+
+// Tasks named by path, in the order they are added
+func orderTestTasks() []*task.Task {
+	mk := func(path string, depth, rank int, source task.TaskSource) *task.Task {
+		t := task.NewTaskFromURL(&url.URL{Path: path})
+		t.Depth = depth
+		t.Rank = rank
+		t.Source = source
+		return t
+	}
+	return []*task.Task{
+		mk("/a/b/c", 3, 5, task.SourceWordlist),
+		mk("/x", 1, 9, task.SourceHTML),
+		mk("/a/b", 2, 1, task.SourceWordlist),
+		mk("/robots", 1, 0, task.SourceRobots),
+		mk("/y", 1, 2, task.SourceHTML),
+	}
+}
+
+func TestTaskList_Orders(t *testing.T) {
+	cases := []struct {
+		order    ss.QueueOrderOption
+		expected []string
+	}{
+		{ss.QueueOrderFIFO, []string{"/a/b/c", "/x", "/a/b", "/robots", "/y"}},
+		{ss.QueueOrderDepth, []string{"/x", "/robots", "/y", "/a/b", "/a/b/c"}},
+		{ss.QueueOrderRank, []string{"/robots", "/a/b", "/y", "/a/b/c", "/x"}},
+		{ss.QueueOrderSource, []string{"/robots", "/x", "/y", "/a/b/c", "/a/b"}},
+	}
+	for _, c := range cases {
+		l := newTaskList(c.order)
+		if l.peek() != nil || l.pop() != nil {
+			t.Errorf("%s: expected empty list.", c.order.String())
+		}
+		for _, tk := range orderTestTasks() {
+			l.push(tk)
+		}
+		if l.len() != len(c.expected) {
+			t.Errorf("%s: expected %d tasks, got %d", c.order.String(), len(c.expected), l.len())
+		}
+		for _, exp := range c.expected {
+			if p := l.peek(); p == nil || p.URL.Path != exp {
+				t.Errorf("%s: expected to peek %s, got %v", c.order.String(), exp, p)
+			}
+			if p := l.pop(); p == nil || p.URL.Path != exp {
+				t.Errorf("%s: expected %s, got %v", c.order.String(), exp, p)
+			}
+		}
+		if l.len() != 0 {
+			t.Errorf("%s: expected empty list, got %d", c.order.String(), l.len())
+		}
+	}
+}
+
+func TestTaskList_Interleaved(t *testing.T) {
+	l := newTaskList(ss.QueueOrderDepth)
+	tasks := orderTestTasks()
+	l.push(tasks[0])
+	l.push(tasks[2])
+	if p := l.pop(); p.URL.Path != "/a/b" {
+		t.Errorf("Expected /a/b, got %s", p.URL.Path)
+	}
+	// A shallow task added late is dispatched ahead of deeper ones
+	l.push(tasks[1])
+	if p := l.pop(); p.URL.Path != "/x" {
+		t.Errorf("Expected /x, got %s", p.URL.Path)
+	}
+	if p := l.pop(); p.URL.Path != "/a/b/c" {
+		t.Errorf("Expected /a/b/c, got %s", p.URL.Path)
+	}
+}
@@ -39,13 +39,15 @@ func (ctr *WorkCounter) Add(todo int64) {
 // Increment the count that is done (output)
 func (ctr *WorkCounter) Done(done int64) {
 	ctr.Lock()
-	defer ctr.Unlock()
 	ctr.done += done
 	ctr.Stats()
 	if ctr.done > ctr.todo {
+		ctr.Unlock()
 		panic("Done exceeded todo in WorkCounter!")
 	}
-	if ctr.done == ctr.todo {
+	finished := ctr.done == ctr.todo
+	ctr.Unlock()
+	if finished {
 		// Mark done
 		logging.Logf(logging.LogInfo, "Work counter thinks we're done.")
 		// These are part of the sync.Cond
@@ -55,6 +57,22 @@ func (ctr *WorkCounter) Done(done int64) {
 	}
 }
 
+// Check if all work is done
+func (ctr *WorkCounter) finished() bool {
+	ctr.Lock()
+	defer ctr.Unlock()
+	return ctr.done == ctr.todo
+}
+
+// Wait until all work is done.  Must not be called with the counter locked.
+func (ctr *WorkCounter) WaitDone() {
+	ctr.L.Lock()
+	defer ctr.L.Unlock()
+	for !ctr.finished() {
+		ctr.Wait()
+	}
+}
+
 // Update the stats of the counter
 func (ctr *WorkCounter) Stats() {
 	logging.Logf(logging.LogDebug, "WorkCounter: %d/%d", ctr.done, ctr.todo)
@@ -63,6 +81,23 @@ func (ctr *WorkCounter) Stats() {
 	}
 }
 
+// Count work done by an earlier run of a resumed scan, so that progress
+// includes it.
+func (ctr *WorkCounter) Restore(done int64) {
+	ctr.Lock()
+	defer ctr.Unlock()
+	ctr.done += done
+	ctr.todo += done
+	ctr.Stats()
+}
+
+// Get the amount of work done and to be done
+func (ctr *WorkCounter) Counts() (done, todo int64) {
+	ctr.Lock()
+	defer ctr.Unlock()
+	return ctr.done, ctr.todo
+}
+
 // Set the status callback for this workcounter
 func (ctr *WorkCounter) SetStatusCallback(f func(int64, int64)) {
 	ctr.doneCb = f
@@ -20,45 +20,61 @@ import (
 	"github.com/Matir/webborer/client"
 	"github.com/Matir/webborer/logging"
 	"github.com/Matir/webborer/robots"
+	"github.com/Matir/webborer/scope"
+	ss "github.com/Matir/webborer/settings"
+	"github.com/Matir/webborer/sitemap"
 	"github.com/Matir/webborer/task"
-	"github.com/Matir/webborer/util"
+	"io"
+	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 )
 
 // WorkQueue is a singleton that maintains the queue of work to be done.
 // It reads from one input channel, verifies that the URL is in scope,
 // queues it, then writes it to the work channel to be done.
-// Internally, it implements a singly-linked list.
+// Internally, it keeps an unbounded list of tasks for each host, which is FIFO
+// by default, and serves the hosts in turn.
 type WorkQueue struct {
 	// Elements to be worked on
-	head *queueNode
-	// End for cheap appends
-	tail *queueNode
-	// Number of items in queue, for stats
-	queueLen int
+	list taskList
 	// Channel for URLs to be considered
 	src chan *task.Task
 	// Channel for URLs to be worked on
 	dst chan *task.Task
 	// filter to determine if a URL should be processed
 	filter func(*task.Task) bool
+	// follows accepted tasks until they are finished, may be nil
+	tracker Tracker
 	// channel to track done
 	started chan bool
 	// counter of work being done
 	ctr WorkCounter
-}
-
-type queueNode struct {
-	// next ptr
-	next *queueNode
-	// data
-	data *task.Task
+	// closed to stop dispatching work
+	stop chan struct{}
+	// guards closing stop
+	stopOnce sync.Once
+	// number of tasks dropped after stopping
+	dropped int64
 }
 
 type QueueAddFunc func(...*task.Task)
-type QueueAddCount func(int)
-type QueueDoneFunc func(int)
+
+// Count n more tasks expanded from t.
+type QueueAddCount func(t *task.Task, n int)
+
+// Count t as done.
+type QueueDoneFunc func(t *task.Task)
+
+// Tracker follows each task accepted into the WorkQueue until it and all of
+// the tasks expanded from it are done.  Accepted sets the task's Origin, which
+// is copied to the tasks expanded from it.
+type Tracker interface {
+	Accepted(t *task.Task)
+	Expanded(t *task.Task, n int)
+	Finished(t *task.Task)
+}
 
 func NewWorkQueue(queueSize int, scope []*url.URL, allowUpgrades bool) *WorkQueue {
 	q := &WorkQueue{
@@ -66,6 +82,8 @@ func NewWorkQueue(queueSize int, scope []*url.URL, allowUpgrades bool) *WorkQueu
 		dst:     make(chan *task.Task, queueSize),
 		filter:  makeScopeFunc(scope, allowUpgrades),
 		started: make(chan bool, 1),
+		stop:    make(chan struct{}),
+		list:    newHostList(func() taskList { return &fifoList{} }),
 	}
 	q.ctr.L = &sync.Mutex{}
 	return q
@@ -88,6 +106,9 @@ func (q *WorkQueue) GetWorkChan() <-chan *task.Task {
 
 func (q *WorkQueue) Run() {
 	defer close(q.dst)
+	if c, ok := q.list.(io.Closer); ok {
+		defer c.Close()
+	}
 
 	q.started <- true
 	keepGoing := true
@@ -96,25 +117,65 @@ func (q *WorkQueue) Run() {
 	}
 }
 
+// Stop dispatching work.  Queued tasks and tasks added later are counted as
+// done without being sent to the work channel, so the pipeline drains.
+func (q *WorkQueue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+	})
+}
+
+// Number of tasks dropped because the queue was stopped.
+func (q *WorkQueue) Dropped() int64 {
+	return atomic.LoadInt64(&q.dropped)
+}
+
+// Drop a task after stopping, counting it as done.
+func (q *WorkQueue) drop(t *task.Task) {
+	atomic.AddInt64(&q.dropped, 1)
+	q.reject(t)
+}
+
+func (q *WorkQueue) stopped() bool {
+	select {
+	case <-q.stop:
+		return true
+	default:
+		return false
+	}
+}
+
 // Run a single step of the queue, returning true if we should continue
 func (q *WorkQueue) runStep() bool {
-	if q.head != nil {
+	if q.stopped() {
+		for q.list.len() > 0 {
+			q.drop(q.pop())
+		}
+		u, ok := <-q.src
+		if !ok {
+			return false
+		}
+		q.drop(u)
+		return true
+	}
+	if q.list.len() > 0 {
 		// If we have work to send, non-blocking read
 		select {
 		case u, ok := <-q.src:
 			if !ok {
-				for q.head != nil {
+				for q.list.len() > 0 {
 					q.dst <- q.pop()
 				}
 				return false
 			}
-			if q.filter(u) {
+			if q.accept(u) {
 				q.push(u)
 			} else {
 				q.reject(u)
 			}
 		case q.dst <- q.peek():
 			q.pop()
+		case <-q.stop:
 		}
 	} else {
 		// Blocking read and non-blocking send
@@ -122,7 +183,7 @@ func (q *WorkQueue) runStep() bool {
 		if !ok {
 			return false
 		}
-		if !q.filter(u) {
+		if !q.accept(u) {
 			q.reject(u)
 			return true
 		}
@@ -135,18 +196,30 @@ func (q *WorkQueue) runStep() bool {
 	return true
 }
 
+// Check if a task should be queued
+func (q *WorkQueue) accept(t *task.Task) bool {
+	if !q.filter(t) {
+		return false
+	}
+	if q.tracker != nil {
+		q.tracker.Accepted(t)
+	}
+	return true
+}
+
+// Follow each task accepted into the queue with a Tracker.  Must be set
+// before the queue is run.
+func (q *WorkQueue) SetTracker(tracker Tracker) {
+	q.tracker = tracker
+}
+
 func (q *WorkQueue) RunInBackground() {
 	go q.Run()
 }
 
 func (q *WorkQueue) WaitPipe() {
 	<-q.started
-	q.ctr.L.Lock()
-	if q.ctr.todo == q.ctr.done {
-		q.ctr.L.Unlock()
-		return
-	}
-	q.ctr.Wait()
+	q.ctr.WaitDone()
 }
 
 func (q *WorkQueue) GetAddFunc() QueueAddFunc {
@@ -156,14 +229,21 @@ func (q *WorkQueue) GetAddFunc() QueueAddFunc {
 }
 
 func (q *WorkQueue) GetAddCount() QueueAddCount {
-	return func(c int) {
-		q.ctr.Add(int64(c))
+	return func(t *task.Task, n int) {
+		if q.tracker != nil {
+			q.tracker.Expanded(t, n)
+		}
+		q.ctr.Add(int64(n))
 	}
 }
 
 func (q *WorkQueue) GetDoneFunc() QueueDoneFunc {
-	return func(c int) {
-		q.ctr.Done(int64(c))
+	return func(t *task.Task) {
+		// Tracked before the counter, which may finish the scan
+		if q.tracker != nil {
+			q.tracker.Finished(t)
+		}
+		q.ctr.Done(1)
 	}
 }
 
@@ -177,48 +257,91 @@ func (q *WorkQueue) SeedFromRobots(scope []*url.URL, clientFactory client.Client
 				pathURL := *scopeURL
 				pathURL.Path = path
 				// Filter will handle if this is out of scope
-				q.AddTasks(task.NewTaskFromURL(scopeURL.ResolveReference(&pathURL)))
+				t := task.NewTaskFromURL(scopeURL.ResolveReference(&pathURL))
+				t.Source = task.SourceRobots
+				q.AddTasks(t)
 			}
 		}
 	}
 }
 
+// Seed the queue with the URLs listed in the sitemaps of each site in scope,
+// found in robots.txt or at /sitemap.xml.
+func (q *WorkQueue) SeedFromSitemaps(scope []*url.URL, clientFactory client.ClientFactory) {
+	sites := make(map[string]bool)
+	for _, scopeURL := range scope {
+		site := scopeURL.Scheme + "://" + scopeURL.Host
+		if sites[site] {
+			continue
+		}
+		sites[site] = true
+		var listed []string
+		if robotsData, err := robots.GetRobotsForURL(scopeURL, clientFactory); err != nil {
+			logging.Logf(logging.LogWarning, "Unable to get robots.txt data: %s", err)
+		} else {
+			listed = robotsData.Sitemaps
+		}
+		inScope := func(u *url.URL) bool {
+			return q.filter(task.NewTaskFromURL(u))
+		}
+		found := sitemap.GetURLsForSite(scopeURL, listed, clientFactory, inScope)
+		logging.Logf(logging.LogInfo, "Found %d URLs in sitemaps for %s", len(found), site)
+		for _, u := range found {
+			// Filter will handle if this is out of scope
+			t := task.NewTaskFromURL(u)
+			t.Source = task.SourceSitemap
+			q.AddTasks(t)
+		}
+	}
+}
+
 func (q *WorkQueue) reject(u *task.Task) {
 	logging.Logf(logging.LogDebug, "Workqueue rejecting %s", u.String())
 	q.ctr.Done(1)
 }
 
-// Append Task to end of queue
+// Add a Task to the queue
 func (q *WorkQueue) push(u *task.Task) {
-	node := &queueNode{data: u}
-	if q.tail != nil {
-		q.tail.next = node
-	} else {
-		q.head = node
-	}
-	q.tail = node
-	q.queueLen++
+	q.list.push(u)
 }
 
-// Get URL from front of queue
+// Get the next Task from the queue
 func (q *WorkQueue) pop() *task.Task {
-	node := q.head
-	if node == nil {
-		return nil
-	}
-	q.head = q.head.next
-	if q.head == nil {
-		q.tail = nil
-	}
-	q.queueLen--
-	return node.data
+	return q.list.pop()
 }
 
-// Get URL from front of queue without removal
+// Get the next Task from the queue without removal
 func (q *WorkQueue) peek() *task.Task {
-	if q.head != nil {
-		return q.head.data
+	return q.list.peek()
+}
+
+// Set the order in which each host's tasks are dispatched.  Must be called
+// before the queue is run.
+func (q *WorkQueue) SetOrder(order ss.QueueOrderOption) {
+	q.list = newHostList(func() taskList { return newTaskList(order) })
+}
+
+// Serve some hosts more often than others.  Hosts not listed have a weight
+// of 1.  Must be called before the queue is run, and before SetSpill.
+func (q *WorkQueue) SetHostWeights(weights map[string]int) {
+	if l, ok := q.list.(*hostList); ok {
+		l.setWeights(weights)
+	}
+}
+
+// Keep at most threshold tasks in memory, writing the rest to a file in dir.
+// Secret headers are not written, and tasks read back get those in header.
+// Must be called before the queue is run, and after SetOrder.
+func (q *WorkQueue) SetSpill(threshold int, dir string, header http.Header) error {
+	if threshold <= 0 {
+		return nil
+	}
+	// Lost tasks are counted as done, so the scan can finish
+	l, err := newSpillList(q.list, threshold, dir, header, q.GetDoneFunc())
+	if err != nil {
+		return err
 	}
+	q.list = l
 	return nil
 }
 
@@ -227,26 +350,21 @@ func (q *WorkQueue) GetCounter() *WorkCounter {
 	return &q.ctr
 }
 
+// Only queue tasks in the given scope.  Must be called before the queue is
+// run.
+func (q *WorkQueue) SetScope(s *scope.Scope) {
+	q.filter = func(t *task.Task) bool {
+		return s.Contains(t.URL)
+	}
+}
+
 // Build a function to check if the target URL is in scope.
-func makeScopeFunc(scope []*url.URL, allowUpgrades bool) func(*task.Task) bool {
-	allowedScopes := make([]*url.URL, len(scope))
-	copy(allowedScopes, scope)
-	if allowUpgrades {
-		for _, scopeURL := range scope {
-			if scopeURL.Scheme == "http" {
-				deref := *scopeURL
-				clone := &deref // Can't find a way to do this in one statement
-				clone.Scheme = "https"
-				allowedScopes = append(allowedScopes, clone)
-			}
-		}
+func makeScopeFunc(bases []*url.URL, allowUpgrades bool) func(*task.Task) bool {
+	s := scope.New()
+	for _, u := range bases {
+		s.IncludeURL(u, allowUpgrades)
 	}
 	return func(target *task.Task) bool {
-		for _, scopeURL := range allowedScopes {
-			if util.URLIsSubpath(scopeURL, target.URL) {
-				return true
-			}
-		}
-		return false
+		return s.Contains(target.URL)
 	}
 }
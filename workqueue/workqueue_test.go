@@ -64,9 +64,9 @@ func TestWorkqueue_Reject(t *testing.T) {
 	queue.InputFinished()
 	out := queue.GetWorkChan()
 	var i int
-	for range out {
+	for tk := range out {
 		i++
-		queue.GetDoneFunc()(1)
+		queue.GetDoneFunc()(tk)
 	}
 	queue.WaitPipe()
 	if i > 0 {
@@ -93,9 +93,9 @@ func TestWorkqueue_PartialReject(t *testing.T) {
 	queue.InputFinished()
 	out := queue.GetWorkChan()
 	var i int
-	for range out {
+	for tk := range out {
 		i++
-		queue.GetDoneFunc()(1)
+		queue.GetDoneFunc()(tk)
 	}
 	queue.WaitPipe()
 	if i != (rounds / 2) {
@@ -142,3 +142,29 @@ func TestMakeScopeFunc(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkqueue_Stop(t *testing.T) {
+	filter := func(_ *task.Task) bool { return true }
+
+	queue := NewWorkQueue(5, nil, false)
+	queue.filter = filter
+	queue.RunInBackground()
+	for i := 0; i < 20; i++ {
+		u := task.NewTaskFromURL(&url.URL{Path: fmt.Sprintf("%d", i)})
+		queue.AddTasks(u)
+	}
+	queue.Stop()
+	queue.Stop()
+	queue.AddTasks(task.NewTaskFromURL(&url.URL{Path: "late"}))
+	// Mark anything that was already dispatched as done
+	go func() {
+		for tk := range queue.GetWorkChan() {
+			queue.GetDoneFunc()(tk)
+		}
+	}()
+	queue.WaitPipe()
+	queue.InputFinished()
+	if queue.Dropped() == 0 {
+		t.Error("Expected tasks to be dropped after stopping.")
+	}
+}